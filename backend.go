@@ -0,0 +1,164 @@
+package schemagen
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/rjeczalik/bindata"
+)
+
+// Backend emits Go source files of a service. Generate calls it once for each
+// service found in the input directory, possibly concurrently for different
+// services.
+type Backend interface {
+	// Generate writes files of service serv into dir, which exists already.
+	// Schemas maps names of service's schemas to their JSON documents merged
+	// with the definitions they use.
+	Generate(serv, dir string, schemas map[string][]byte) error
+}
+
+// BindataBackend is the default Backend. It stores schemas as compressed data
+// generated by bindata in `schemaFile` file and binds them into documents
+// of Runtime in `outputFile` file.
+type BindataBackend struct {
+	BindOptions
+}
+
+// BindOptions configure `outputFile` files emitted by the default backends.
+type BindOptions struct {
+	// Native if enabled makes validation functions use code generated into
+	// `nativeFile` file.
+	Native bool
+
+	// Runtime is a library, which validates documents.
+	Runtime Runtime
+
+	// Lazy if enabled makes schemas compiled on first use by Schema
+	// function, which reports failures instead of panicking at init.
+	Lazy bool
+
+	// Template if set is used instead of the default template, it is
+	// executed with *BindData.
+	Template *template.Template
+}
+
+// Generate implements Backend interface.
+func (b *BindataBackend) Generate(serv, dir string, schemas map[string][]byte) (err error) {
+	// bindata reads the schemas from files, they are named after schemas.
+	tmp, err := ioutil.TempDir("", "schema_bin")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(tmp)
+	for name, data := range schemas {
+		if err = ioutil.WriteFile(filepath.Join(tmp, name), data, 0644); err != nil {
+			return fileError(tmp, nil, err)
+		}
+	}
+	c := &bindata.Config{
+		Package:   serv,
+		Input:     []bindata.InputConfig{bindata.InputConfig{Path: tmp}},
+		Output:    filepath.Join(dir, schemaFile),
+		Prefix:    tmp,
+		Recursive: true,
+		Fmt:       true,
+	}
+	if err = bindata.Generate(c); err != nil {
+		return &Error{Path: c.Output, Err: err}
+	}
+	return writeBind(serv, dir, schemas, b.BindOptions)
+}
+
+// writeBind writes `outputFile` file of service serv into dir, which binds
+// schemas exposed by `_bindata` map as configured by opts.
+func writeBind(serv, dir string, schemas map[string][]byte, opts BindOptions) error {
+	name := filepath.Join(dir, outputFile)
+	src, err := bindSource(serv, schemas, opts)
+	if err != nil {
+		return newError(name, cannotWriteToFileErr, err)
+	}
+	if err = ioutil.WriteFile(name, src, 0644); err != nil {
+		return newError(name, cannotWriteToFileErr, err)
+	}
+	return nil
+}
+
+// backend returns Backend used by Generate, which is the one set by user or
+// the default one selected by Embed option.
+func (s *schg) backend() (Backend, error) {
+	if s.Backend != nil {
+		return s.Backend, nil
+	}
+	opts := BindOptions{Native: s.Native, Runtime: s.Runtime, Lazy: s.Lazy}
+	if s.Template != "" {
+		tmpl, err := parseTemplate(s.Template)
+		if err != nil {
+			return nil, err
+		}
+		opts.Template = tmpl
+	}
+	if s.Embed {
+		return &EmbedBackend{opts}, nil
+	}
+	return &BindataBackend{opts}, nil
+}
+
+// serviceSchemas returns schemas of service serv merged during the walk,
+// which are marshaled to JSON and named after their files without extension.
+func (s *schg) serviceSchemas(serv string) (map[string][]byte, error) {
+	schemas := make(map[string][]byte, len(s.sources[serv]))
+	for name, path := range s.sources[serv] {
+		data, err := json.Marshal(s.merged[strings.TrimSuffix(path, filepath.Ext(path))])
+		if err != nil {
+			return nil, fileError(path, nil, err)
+		}
+		schemas[name] = data
+	}
+	return schemas, nil
+}
+
+// saveServices passes schemas of each service to the backend, which writes
+// them into service's output directory.
+func (s *schg) saveServices(schemaOutBase string) error {
+	type job struct{ serv, dir string }
+	ch, ret := make(chan job, len(s.sources)), make(chan error)
+	servs := make([]string, 0, len(s.sources))
+	for serv := range s.sources {
+		servs = append(servs, serv)
+	}
+	sort.Strings(servs)
+	for _, serv := range servs {
+		subdir := serv
+		if s.merge || serv == filepath.Base(schemaOutBase) {
+			subdir = ""
+		}
+		ch <- job{serv: serv, dir: filepath.Join(schemaOutBase, subdir)}
+	}
+	close(ch)
+	b, err := s.backend()
+	if err != nil {
+		return err
+	}
+	for n := min(runtime.GOMAXPROCS(-1), len(servs)); n > 0; n-- {
+		go func() {
+			for j := range ch {
+				schemas, err := s.serviceSchemas(j.serv)
+				if err == nil {
+					err = fileError(j.dir, nil, b.Generate(j.serv, j.dir, schemas))
+				}
+				ret <- err
+			}
+		}()
+	}
+	var errs Errors
+	for _ = range servs {
+		errs.add(<-ret)
+	}
+	return errs.err()
+}
@@ -0,0 +1,74 @@
+package schemagen
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// recordBackend is a Backend, which records schemas it gets.
+type recordBackend struct {
+	mu      sync.Mutex
+	dirs    map[string]string
+	schemas map[string]map[string]interface{}
+}
+
+func (b *recordBackend) Generate(serv, dir string, schemas map[string][]byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dirs[serv] = dir
+	b.schemas[serv] = make(map[string]interface{})
+	for name, data := range schemas {
+		var schema interface{}
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return err
+		}
+		b.schemas[serv][name] = schema
+	}
+	return nil
+}
+
+func TestGenerateBackend(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		"definitions.json": `{"definitions": {"id": {"type": "integer"}}}`,
+		"users/get.json":   `{"$ref": "#/definitions/id"}`,
+		"orders/list.json": `{"type": "array"}`,
+	})
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+	b := &recordBackend{dirs: make(map[string]string),
+		schemas: make(map[string]map[string]interface{})}
+	g := New(false)
+	g.Backend = b
+	if err := g.Generate(dir, out); err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	dirs := map[string]string{
+		"users":  filepath.Join(out, "users"),
+		"orders": filepath.Join(out, "orders"),
+	}
+	if !reflect.DeepEqual(b.dirs, dirs) {
+		t.Errorf("want dirs=%v; got %v", dirs, b.dirs)
+	}
+	schemas := map[string]map[string]interface{}{
+		"users": {"get": map[string]interface{}{
+			"$ref":        "#/definitions/id",
+			"definitions": map[string]interface{}{"id": map[string]interface{}{"type": "integer"}},
+		}},
+		"orders": {"list": map[string]interface{}{
+			"type":        "array",
+			"definitions": map[string]interface{}{},
+		}},
+	}
+	if !reflect.DeepEqual(b.schemas, schemas) {
+		t.Errorf("want schemas=%v; got %v", schemas, b.schemas)
+	}
+	for _, dir := range dirs {
+		if _, err := os.Stat(filepath.Join(dir, schemaFile)); !os.IsNotExist(err) {
+			t.Errorf("want %s not to exist; got err=%v", schemaFile, err)
+		}
+	}
+}
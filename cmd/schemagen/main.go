@@ -6,6 +6,19 @@
 //	 schemagen --separate                         Run in glob mode creating seperate schemas per service.
 //	 schemagen --input . --output dir             Run for single input directory.
 //	 schemagen --input . --output dir --separate  Run for single input directory creating seperate schemas per service.
+//	 schemagen --inherit                          Run with definitions of subdirectories extending their parent's ones.
+//	 schemagen --conflict local|shared|error      Run resolving conflicts of schemas' own and shared definitions.
+//	 schemagen --unused                           Run reporting definitions not used by any schema.
+//	 schemagen --fail-unused                      Run failing if any definition is not used by any schema.
+//	 schemagen --mirror http://host/path/=dir     Run resolving remote references with local mirror directory.
+//	 schemagen --comments                         Run allowing comments and trailing commas in .json files.
+//	 schemagen --types                            Run generating Go types of schemas in types.go files.
+//	 schemagen --native                           Run generating Go validation code of schemas in native.go files.
+//	 schemagen --embed                            Run embedding schema files with go:embed instead of bindata.
+//	 schemagen --runtime xeipuuv                  Run validating documents with sigu-399, xeipuuv or santhosh-tekuri library.
+//	 schemagen --lazy                             Run compiling schemas of bind.go files on first use instead of at init.
+//	 schemagen --template bind.tmpl               Run generating bind.go files with the text/template file.
+//	 schemagen test --input .                     Validate sample documents of method.valid and method.invalid directories.
 //	 schemagen --help                             Show this message.`
 
 package main
@@ -14,15 +27,29 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/x-formation/schemagen"
 )
 
 var (
-	separate bool
-	in       string
-	out      string
-	h        bool
+	separate   bool
+	inherit    bool
+	conflict   schemagen.Conflict
+	unused     bool
+	failUnused bool
+	mirrors    = mirrorFlag{}
+	comments   bool
+	types      bool
+	native     bool
+	embed      bool
+	rt         schemagen.Runtime
+	lazy       bool
+	tmpl       string
+	in         string
+	out        string
+	h          bool
 )
 
 const usage = `NAME:
@@ -33,11 +60,57 @@ USAGE:
 	schemagen --separate                         Run in glob mode creating seperate schemas per service.
 	schemagen --input . --output dir             Run for single input directory.
 	schemagen --input . --output dir --separate  Run for single input directory creating seperate schemas per service.
+	schemagen --inherit                          Run with definitions of subdirectories extending their parent's ones.
+	schemagen --conflict local|shared|error      Run resolving conflicts of schemas' own and shared definitions.
+	schemagen --unused                           Run reporting definitions not used by any schema.
+	schemagen --fail-unused                      Run failing if any definition is not used by any schema.
+	schemagen --mirror http://host/path/=dir     Run resolving remote references with local mirror directory.
+	schemagen --comments                         Run allowing comments and trailing commas in .json files.
+	schemagen --types                            Run generating Go types of schemas in types.go files.
+	schemagen --native                           Run generating Go validation code of schemas in native.go files.
+	schemagen --embed                            Run embedding schema files with go:embed instead of bindata.
+	schemagen --runtime xeipuuv                  Run validating documents with sigu-399, xeipuuv or santhosh-tekuri library.
+	schemagen --lazy                             Run compiling schemas of bind.go files on first use instead of at init.
+	schemagen --template bind.tmpl               Run generating bind.go files with the text/template file.
+	schemagen test --input .                     Validate sample documents of method.valid and method.invalid directories.
 	schemagen --help                             Show this message.
 `
 
+// mirrorFlag is a flag.Value which collects URL prefixes and directories.
+type mirrorFlag map[string]string
+
+func (m mirrorFlag) String() string {
+	var s []string
+	for prefix, dir := range m {
+		s = append(s, prefix+"="+dir)
+	}
+	sort.Strings(s)
+	return strings.Join(s, ",")
+}
+
+func (m mirrorFlag) Set(v string) error {
+	i := strings.LastIndex(v, "=")
+	if i <= 0 || i == len(v)-1 {
+		return fmt.Errorf("invalid mirror %q, want prefix=dir", v)
+	}
+	m[v[:i]] = v[i+1:]
+	return nil
+}
+
 func init() {
 	flag.BoolVar(&separate, "separate", separate, "Generate go schemas per service.")
+	flag.BoolVar(&inherit, "inherit", inherit, "Extend parent's definitions with subdirectories' ones.")
+	flag.Var(&conflict, "conflict", "Policy of local and shared definitions conflicts (local, shared, error).")
+	flag.BoolVar(&unused, "unused", unused, "Report definitions not used by any schema.")
+	flag.BoolVar(&failUnused, "fail-unused", failUnused, "Fail if any definition is not used by any schema.")
+	flag.Var(mirrors, "mirror", "URL prefix and local directory which mirrors it, separated with '='.")
+	flag.BoolVar(&comments, "comments", comments, "Allow comments and trailing commas in .json files.")
+	flag.BoolVar(&types, "types", types, "Generate Go types of schemas.")
+	flag.BoolVar(&native, "native", native, "Generate Go validation code of schemas.")
+	flag.BoolVar(&embed, "embed", embed, "Embed schema files with go:embed instead of bindata.")
+	flag.Var(&rt, "runtime", "Library validating documents (sigu-399, xeipuuv, santhosh-tekuri).")
+	flag.BoolVar(&lazy, "lazy", lazy, "Compile schemas on first use instead of at init.")
+	flag.StringVar(&tmpl, "template", tmpl, "Template file of bind.go files.")
 	flag.StringVar(&in, "input", in, "JSON files input directory.")
 	flag.StringVar(&out, "output", out, "Go source files output directory.")
 	flag.BoolVar(&h, "help", h, "Show this message.")
@@ -48,24 +121,106 @@ func init() {
 }
 
 func main() {
-	flag.Parse()
+	var test bool
+	if len(os.Args) > 1 && os.Args[1] == "test" {
+		test = true
+		flag.CommandLine.Parse(os.Args[2:])
+	} else {
+		flag.Parse()
+	}
 	if h {
 		fmt.Print(usage)
 		return
 	}
-	if flag.NArg() != 0 || (in != "") != (out != "") {
+	if flag.NArg() != 0 || (!test && (in != "") != (out != "")) || (test && out != "") {
 		fmt.Fprintf(os.Stderr, usage)
 		os.Exit(1)
 	}
 	var err error
+	g := schemagen.New(!separate)
+	g.Inherit, g.Conflict, g.Mirrors = inherit, conflict, mirrors
+	g.Comments, g.Types, g.Native = comments, types, native
+	g.Embed, g.Runtime, g.Lazy = embed, rt, lazy
+	g.Template = tmpl
+	if test {
+		if in == "" {
+			in = "."
+		}
+		if err = g.Test(in); err != nil {
+			reportErrors(err)
+			os.Exit(1)
+		}
+		return
+	}
 	if in != "" {
-		err = schemagen.New(!separate).Generate(in, out)
+		err = g.Generate(in, out)
 	} else {
-		err = schemagen.Glob(!separate)
+		err = g.Glob()
 	}
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
+		reportErrors(err)
 		os.Exit(1)
 	}
+	if unused || failUnused {
+		if n := reportUnused(g.Unused()); n != 0 && failUnused {
+			os.Exit(1)
+		}
+	}
+	return
+}
+
+// reportErrors prints err. Failures collected in schemagen.Errors are grouped
+// by files they concern.
+func reportErrors(err error) {
+	errs, ok := err.(schemagen.Errors)
+	if !ok {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return
+	}
+	var files []string
+	groups := make(map[string][]string)
+	for _, err := range errs {
+		file, msg := "", err.Error()
+		if e, ok := err.(*schemagen.Error); ok {
+			file, msg = e.Path, e.Err.Error()
+			switch {
+			case e.Column != 0:
+				msg = fmt.Sprintf("%d:%d: %s", e.Line, e.Column, msg)
+			case e.Line != 0:
+				msg = fmt.Sprintf("%d: %s", e.Line, msg)
+			}
+		}
+		if _, ok := groups[file]; !ok {
+			files = append(files, file)
+		}
+		groups[file] = append(groups[file], msg)
+	}
+	sort.Strings(files)
+	fmt.Fprintf(os.Stderr, "schemagen: %d error(s):\n", len(errs))
+	for _, file := range files {
+		indent := ""
+		if file != "" {
+			fmt.Fprintf(os.Stderr, "%s:\n", file)
+			indent = "\t"
+		}
+		for _, msg := range groups[file] {
+			fmt.Fprintf(os.Stderr, "%s%s\n", indent, msg)
+		}
+	}
+}
+
+// reportUnused prints unused definitions and returns their number.
+func reportUnused(defs map[string][]string) (n int) {
+	files := make([]string, 0, len(defs))
+	for file := range defs {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		for _, name := range defs[file] {
+			fmt.Fprintf(os.Stderr, "schemagen: %s: unused definition %q\n", file, name)
+			n++
+		}
+	}
 	return
 }
@@ -0,0 +1,167 @@
+package schemagen
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// edge is a reference from one definition to another. It is required if every
+// instance of the referring definition must contain an instance of the referred
+// one, that is when the reference is reached only through required properties,
+// allOf members and non-empty arrays.
+type edge struct {
+	to       string
+	required bool
+}
+
+// findEdges returns references of definition def to other definitions.
+func findEdges(def interface{}) (edges []edge) {
+	walkEdges(def, true, func(e edge) { edges = append(edges, e) })
+	return
+}
+
+// walkEdges calls fn for every reference to a definition found in schema v.
+// The required flag tells whether instance of v must exist in the instance
+// of the definition being walked.
+func walkEdges(v interface{}, required bool, fn func(edge)) {
+	schema, ok := v.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if ref, ok := schema[`$ref`].(string); ok {
+		if name, ok := definitionName(ref); ok {
+			fn(edge{to: name, required: required})
+		}
+	}
+	req := make(map[string]bool)
+	if names, ok := schema[`required`].([]interface{}); ok {
+		for _, name := range names {
+			if name, ok := name.(string); ok {
+				req[name] = true
+			}
+		}
+	}
+	if props, ok := schema[`properties`].(map[string]interface{}); ok {
+		for name, prop := range props {
+			walkEdges(prop, required && req[name], fn)
+		}
+	}
+	if all, ok := schema[`allOf`].([]interface{}); ok {
+		for _, sub := range all {
+			walkEdges(sub, required, fn)
+		}
+	}
+	// a single alternative must be satisfied, otherwise others may end
+	// the recursion.
+	for _, key := range []string{`anyOf`, `oneOf`} {
+		if alt, ok := schema[key].([]interface{}); ok {
+			for _, sub := range alt {
+				walkEdges(sub, required && len(alt) == 1, fn)
+			}
+		}
+	}
+	minItems, _ := schema[`minItems`].(float64)
+	switch items := schema[`items`].(type) {
+	case map[string]interface{}:
+		walkEdges(items, required && minItems > 0, fn)
+	case []interface{}:
+		for i, sub := range items {
+			walkEdges(sub, required && float64(i) < minItems, fn)
+		}
+	}
+	for _, key := range []string{`additionalProperties`, `additionalItems`, `not`,
+		`if`, `then`, `else`, `contains`, `propertyNames`} {
+		walkEdges(schema[key], false, fn)
+	}
+	for _, key := range []string{`patternProperties`, `dependencies`} {
+		if m, ok := schema[key].(map[string]interface{}); ok {
+			for _, sub := range m {
+				walkEdges(sub, false, fn)
+			}
+		}
+	}
+}
+
+// cycle is a chain of definition names, which starts and ends with the same
+// definition.
+type cycle []string
+
+// findCycles returns cycles formed by required references between definitions
+// of l. Recursion through references which are not required is legal, as an
+// instance may end it, so it is not reported.
+func findCycles(l *layer) (cycles []cycle) {
+	var names []string
+	graph := make(map[string][]string)
+	for name, def := range l.defs {
+		names = append(names, name)
+		for _, e := range findEdges(def) {
+			if _, ok := l.defs[e.to]; ok && e.required {
+				graph[name] = append(graph[name], e.to)
+			}
+		}
+		sort.Strings(graph[name])
+	}
+	sort.Strings(names)
+	// visited definitions are either on stack (true) or done (false).
+	visited := make(map[string]bool)
+	var stack []string
+	var visit func(string)
+	visit = func(name string) {
+		visited[name] = true
+		stack = append(stack, name)
+		for _, to := range graph[name] {
+			onStack, ok := visited[to]
+			switch {
+			case !ok:
+				visit(to)
+			case onStack:
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == to {
+						c := append(cycle(nil), stack[i:]...)
+						cycles = append(cycles, append(c, to))
+						break
+					}
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		visited[name] = false
+	}
+	for _, name := range names {
+		if _, ok := visited[name]; !ok {
+			visit(name)
+		}
+	}
+	return
+}
+
+// format writes cycle as a chain of definition names followed by names of
+// files, relative to base directory, the definitions come from.
+func (c cycle) format(l *layer, base string) string {
+	chain := make([]string, len(c))
+	for i, name := range c {
+		file := l.files[name]
+		if rel, err := filepath.Rel(base, file); err == nil {
+			file = rel
+		}
+		chain[i] = fmt.Sprintf(`%s (%s)`, name, file)
+	}
+	return strings.Join(chain, ` -> `)
+}
+
+// checkCycles fails if required references between definitions of l, which
+// were read from file, form a cycle, as no finite instance can satisfy such
+// definitions.
+func (s *schg) checkCycles(l *layer, file string) error {
+	cycles := findCycles(l)
+	if len(cycles) == 0 {
+		return nil
+	}
+	chains := make([]string, len(cycles))
+	for i, c := range cycles {
+		chains[i] = c.format(l, s.inBase)
+	}
+	return newError(file, requiredCycleErr, strings.Join(chains, `; `))
+}
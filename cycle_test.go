@@ -0,0 +1,86 @@
+package schemagen
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newLayerTest(t *testing.T, defs string) *layer {
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(defs), &m); err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	return newLayer(m, definitionsFile)
+}
+
+func TestFindCycles(t *testing.T) {
+	tests := map[string][]cycle{
+		// recursion through optional property is legal.
+		`{"node": {"properties": {"next": {"$ref": "#/definitions/node"}}}}`: nil,
+		`{"node": {"required": ["next"],
+			"properties": {"next": {"$ref": "#/definitions/node"}}}}`: {{"node", "node"}},
+		`{"tree": {"required": ["children"], "properties": {"children": {
+			"type": "array", "items": {"$ref": "#/definitions/tree"}}}}}`: nil,
+		`{"tree": {"required": ["children"], "properties": {"children": {
+			"type": "array", "minItems": 1, "items": {"$ref": "#/definitions/tree"}}}}}`: {{"tree", "tree"}},
+		`{"a": {"allOf": [{"$ref": "#/definitions/b"}]},
+			"b": {"required": ["a"], "properties": {"a": {"$ref": "#/definitions/a/properties"}}}}`: {{"a", "b", "a"}},
+		`{"a": {"anyOf": [{"type": "null"}, {"$ref": "#/definitions/b"}]},
+			"b": {"$ref": "#/definitions/a"}}`: nil,
+		`{"a": {"oneOf": [{"$ref": "#/definitions/b"}]},
+			"b": {"required": ["x"], "properties": {"x": {
+				"properties": {"y": {"$ref": "#/definitions/a"}}, "required": ["y"]}}}}`: {{"a", "b", "a"}},
+		`{"a": {"required": ["x"], "properties": {"x": {
+				"properties": {"y": {"$ref": "#/definitions/a"}}}}}}`: nil,
+		`{"a": {"not": {"$ref": "#/definitions/a"}},
+			"b": {"additionalProperties": {"$ref": "#/definitions/b"}}}`: nil,
+	}
+	for defs, exp := range tests {
+		if got := findCycles(newLayerTest(t, defs)); !reflect.DeepEqual(got, exp) {
+			t.Errorf("%s: want cycles=%v; got %v", defs, exp, got)
+		}
+	}
+}
+
+func TestCheckCycles(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		definitionsFile: `{"definitions": {
+			"a": {"required": ["b"], "properties": {"b": {"$ref": "#/definitions/b"}}},
+			"b": {"$ref": "#/definitions/a"},
+			"list": {"properties": {"next": {"$ref": "#/definitions/list"}}}}}`,
+		"service/method.json": `{"$ref": "#/definitions/list"}`,
+	})
+	defer os.RemoveAll(dir)
+	out, err := ioutil.TempDir(os.TempDir(), "out")
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	defer os.RemoveAll(out)
+	err = New(false).Generate(dir, out)
+	exp := "a (definitions.json) -> b (definitions.json) -> a (definitions.json)"
+	if err == nil || !strings.Contains(err.Error(), exp) {
+		t.Fatalf("want err to contain %q; got %v", exp, err)
+	}
+
+	// layered definitions report files they come from.
+	dir = newSchemaTree(t, map[string]string{
+		definitionsFile: `{"definitions": {
+			"a": {"required": ["b"], "properties": {"b": {"$ref": "#/definitions/b"}}},
+			"b": {}}}`,
+		"sub/" + definitionsFile:  `{"definitions": {"b": {"$ref": "#/definitions/a"}}}`,
+		"sub/service/method.json": `{}`,
+	})
+	defer os.RemoveAll(dir)
+	schg := New(false)
+	schg.Inherit = true
+	err = schg.Generate(dir, out)
+	exp = "a (definitions.json) -> b (" + filepath.Join("sub", definitionsFile) + ") -> a (definitions.json)"
+	if err == nil || !strings.Contains(err.Error(), exp) {
+		t.Fatalf("want err to contain %q; got %v", exp, err)
+	}
+}
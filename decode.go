@@ -0,0 +1,324 @@
+package schemagen
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// schemaExts are extensions of files which are read as schemas.
+var schemaExts = map[string]bool{`.json`: true, `.jsonc`: true, `.json5`: true,
+	`.yaml`: true, `.yml`: true}
+
+// definitionsFiles are names of files which may contain definitions, in order
+// of precedence.
+var definitionsFiles = []string{definitionsFile, `definitions.jsonc`,
+	`definitions.json5`, `definitions.yaml`, `definitions.yml`}
+
+// isDefinitionsFile reports whether name is one of definitionsFiles.
+func isDefinitionsFile(name string) bool {
+	for _, f := range definitionsFiles {
+		if name == f {
+			return true
+		}
+	}
+	return false
+}
+
+// definitionsPath returns path of definitions file located in dir. If dir
+// does not contain any, path of `definitionsFile` is returned.
+func definitionsPath(dir string) string {
+	for _, f := range definitionsFiles {
+		path := filepath.Join(dir, f)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(dir, definitionsFile)
+}
+
+// checkDefinitionsFiles fails if dir contains more than one of
+// definitionsFiles, since only the first of them is read.
+func checkDefinitionsFiles(dir string) error {
+	var errs Errors
+	var first string
+	for _, f := range definitionsFiles {
+		path := filepath.Join(dir, f)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if first == "" {
+			first = path
+			continue
+		}
+		errs.add(newError(path, duplicatedDefsFileErr, first))
+	}
+	return errs.err()
+}
+
+// isYAML reports whether file located at path is a YAML one.
+func isYAML(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == `.yaml` || ext == `.yml`
+}
+
+// isTolerant reports whether JSON file located at path may contain comments
+// and trailing commas. These are allowed in .jsonc and .json5 files, and in
+// .json files if s.Comments is enabled.
+func (s *schg) isTolerant(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == `.jsonc` || ext == `.json5` || (s.Comments && ext == `.json`)
+}
+
+// decode unmarshals data read from path. YAML documents are normalized,
+// so they are represented by the same values as JSON ones. Errors are
+// reported with *Error.
+func (s *schg) decode(path string, data []byte) (v interface{}, err error) {
+	if !isYAML(path) {
+		err = s.unmarshalJSON(path, data, &v)
+	} else {
+		v, err = decodeYAML(path, data)
+	}
+	if err != nil {
+		return nil, fileError(path, data, err)
+	}
+	return v, nil
+}
+
+// decodeObject works like decode, but it fails if the document is not
+// an object.
+func (s *schg) decodeObject(path string, data []byte) (m map[string]interface{}, err error) {
+	if isYAML(path) {
+		var v interface{}
+		if v, err = s.decode(path, data); err != nil {
+			return nil, err
+		}
+		m, _ = v.(map[string]interface{})
+	} else if err = s.unmarshalJSON(path, data, &m); err != nil {
+		return nil, fileError(path, data, err)
+	}
+	if m == nil {
+		return nil, newError(path, notObjectErr)
+	}
+	return m, nil
+}
+
+// unmarshalJSON unmarshals JSON data read from path into v. Comments and
+// trailing commas are stripped first if the file may contain them. Offsets
+// reported by syntax errors of such files match the ones of the original data.
+func (s *schg) unmarshalJSON(path string, data []byte, v interface{}) error {
+	if !s.isTolerant(path) {
+		return json.Unmarshal(data, v)
+	}
+	stripped, err := stripComments(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(stripped, v)
+}
+
+// commentError is returned by stripComments for block comment which is not
+// terminated. Like json.SyntaxError it holds offset of the byte after the
+// comment's start.
+type commentError struct {
+	offset int64
+}
+
+func (e *commentError) Error() string {
+	return `unterminated comment`
+}
+
+// stripComments replaces `//` and `/* */` comments and trailing commas found
+// in data outside of strings with spaces. New lines are kept, so offsets,
+// lines and columns of the result match the ones of data.
+func stripComments(data []byte) ([]byte, error) {
+	out := append([]byte(nil), data...)
+	blank := func(from, to int) {
+		for ; from < to; from++ {
+			if out[from] != '\n' && out[from] != '\r' {
+				out[from] = ' '
+			}
+		}
+	}
+	for i := 0; i < len(out); i++ {
+		switch {
+		case out[i] == '"':
+			for i++; i < len(out) && out[i] != '"'; i++ {
+				if out[i] == '\\' {
+					i++
+				}
+			}
+		case out[i] == '/' && i+1 < len(out) && out[i+1] == '/':
+			end := i
+			for end < len(out) && out[end] != '\n' {
+				end++
+			}
+			blank(i, end)
+			i = end
+		case out[i] == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := i + 2
+			for end+1 < len(out) && !(out[end] == '*' && out[end+1] == '/') {
+				end++
+			}
+			if end+1 >= len(out) {
+				return nil, &commentError{offset: int64(i + 1)}
+			}
+			blank(i, end+2)
+			i = end + 1
+		}
+	}
+	// comments are gone, so a comma followed only by white spaces and
+	// closing bracket is a trailing one.
+	for i := 0; i < len(out); i++ {
+		switch out[i] {
+		case '"':
+			for i++; i < len(out) && out[i] != '"'; i++ {
+				if out[i] == '\\' {
+					i++
+				}
+			}
+		case ',':
+			j := i + 1
+			for j < len(out) && (out[j] == ' ' || out[j] == '\t' || out[j] == '\n' || out[j] == '\r') {
+				j++
+			}
+			if j < len(out) && (out[j] == '}' || out[j] == ']') {
+				out[i] = ' '
+			}
+		}
+	}
+	return out, nil
+}
+
+// position returns line and column, both starting at 1, of the byte which
+// precedes offset in data. Syntax errors report offset of the byte right
+// after the one which is invalid.
+func position(data []byte, offset int64) (line, col int) {
+	line, col = 1, 1
+	for i := int64(0); i < offset-1 && i < int64(len(data)); i++ {
+		if data[i] == '\n' {
+			line, col = line+1, 1
+			continue
+		}
+		col++
+	}
+	return
+}
+
+// decodeYAML unmarshals YAML data read from path to the value which
+// encoding/json would create for the same document. Scalars are resolved with
+// YAML 1.2 rules, so e.g. NO and on are strings rather than booleans.
+func decodeYAML(path string, data []byte) (interface{}, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return yamlValue(path, &doc, make(map[*yaml.Node]bool))
+}
+
+// yamlValue converts node of YAML document read from path: mappings become
+// objects which keys are scalars as they are written, e.g. 200 or on, and all
+// the numbers become float64. Aliases are expanded, aliases holds the ones
+// being expanded, which ends walking cyclic ones.
+func yamlValue(path string, n *yaml.Node, aliases map[*yaml.Node]bool) (interface{}, error) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return yamlValue(path, n.Content[0], aliases)
+	case yaml.AliasNode:
+		if aliases[n] {
+			return nil, yamlError(path, n, yamlAliasErr, n.Value)
+		}
+		aliases[n] = true
+		defer delete(aliases, n)
+		return yamlValue(path, n.Alias, aliases)
+	case yaml.SequenceNode:
+		a := make([]interface{}, len(n.Content))
+		for i, cont := range n.Content {
+			var err error
+			if a[i], err = yamlValue(path, cont, aliases); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case yaml.MappingNode:
+		return yamlMapping(path, n, aliases)
+	case yaml.ScalarNode:
+		var v interface{}
+		switch n.ShortTag() {
+		case `!!str`, `!!timestamp`:
+			return n.Value, nil
+		case `!!null`:
+			return nil, nil
+		}
+		if err := n.Decode(&v); err != nil {
+			return nil, yamlError(path, n, `%v`, err)
+		}
+		switch v := v.(type) {
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case uint64:
+			return float64(v), nil
+		}
+		return v, nil
+	}
+	// empty document.
+	return nil, nil
+}
+
+// yamlMapping converts mapping node n of YAML document read from path to
+// an object. Keys of mappings merged with `<<` key are overridden by the ones
+// of n, and by the keys of mappings which precede them.
+func yamlMapping(path string, n *yaml.Node, aliases map[*yaml.Node]bool) (interface{}, error) {
+	m := make(map[string]interface{}, len(n.Content)/2)
+	var merged []map[string]interface{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, cont := n.Content[i], n.Content[i+1]
+		if key.Kind == yaml.AliasNode {
+			key = key.Alias
+		}
+		if key.Kind != yaml.ScalarNode {
+			return nil, yamlError(path, n.Content[i], yamlKeyErr)
+		}
+		v, err := yamlValue(path, cont, aliases)
+		if err != nil {
+			return nil, err
+		}
+		if key.ShortTag() != `!!merge` {
+			m[key.Value] = v
+			continue
+		}
+		vs, ok := v.([]interface{})
+		if !ok {
+			vs = []interface{}{v}
+		}
+		for _, v := range vs {
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, yamlError(path, cont, yamlMergeErr)
+			}
+			merged = append(merged, obj)
+		}
+	}
+	for _, obj := range merged {
+		for key, v := range obj {
+			if _, ok := m[key]; !ok {
+				m[key] = v
+			}
+		}
+	}
+	return m, nil
+}
+
+// yamlError creates an Error located at node n of YAML document read from
+// path.
+func yamlError(path string, n *yaml.Node, format string, args ...interface{}) *Error {
+	return &Error{Path: path, Line: n.Line, Column: n.Column, Err: fmt.Errorf(format, args...)}
+}
@@ -0,0 +1,154 @@
+package schemagen
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := map[string]string{
+		"a: 1\nb: [1.5, true, null, x]\nc: {d: -2}\n":     `{"a": 1, "b": [1.5, true, null, "x"], "c": {"d": -2}}`,
+		"[18446744073709551615]":                          `[18446744073709551615]`,
+		"enum: [NO, SE, DK]\nx: [yes, off, 2001-12-14]\n": `{"enum": ["NO", "SE", "DK"], "x": ["yes", "off", "2001-12-14"]}`,
+		"200: {on: 1}\n1.50: x\ntrue: y\n":                `{"200": {"on": 1}, "1.50": "x", "true": "y"}`,
+		"a: &a {x: 1, y: 2}\nb: {<<: *a, y: 3}\n":         `{"a": {"x": 1, "y": 2}, "b": {"x": 1, "y": 3}}`,
+	}
+	for yml, js := range tests {
+		got, err := New(false).decode("f.yaml", []byte(yml))
+		if err != nil {
+			t.Errorf("want err=nil; got %v", err)
+			continue
+		}
+		var exp interface{}
+		if err = json.Unmarshal([]byte(js), &exp); err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+		if !reflect.DeepEqual(got, exp) {
+			t.Errorf("want %#v; got %#v", exp, got)
+		}
+	}
+	for yml, line := range map[string]int{
+		"a: 1\n? [b]\n: c\n": 2,
+		"a: &a [*a]\n":       1,
+		"a:\n  <<: [1]\n":    2,
+	} {
+		_, err := New(false).decode("f.yml", []byte(yml))
+		if ferr, ok := err.(*Error); !ok || ferr.Line != line {
+			t.Errorf("%q: want *Error at line %d; got %v", yml, line, err)
+		}
+	}
+	for _, path := range []string{"f.yml", "f.json"} {
+		for _, data := range []string{"", "null", "[]"} {
+			if _, err := New(false).decodeObject(path, []byte(data)); err == nil {
+				t.Errorf("%s: %q: want err!=nil", path, data)
+			}
+		}
+	}
+}
+
+func TestWalkYAML(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		"definitions.yaml": "definitions:\n  id: {type: integer, minimum: 1}\n",
+		"service/yaml.yaml": "type: object\nproperties:\n  id: {$ref: '#/definitions/id'}\n" +
+			"  zip: {$ref: '../common/zip.yml'}\n",
+		"service/json.json": `{"type": "object", "properties": {"id": {"$ref": "#/definitions/id"},
+			"zip": {"$ref": "../common/zip.yml"}}}`,
+		"common/zip.yml": "type: string\nmaxLength: 5\n",
+	})
+	defer os.RemoveAll(dir)
+	dumped, err := walkTest(t, New(false), dir)
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	if !reflect.DeepEqual(dumped["service"]["yaml"], dumped["service"]["json"]) {
+		t.Errorf("want %v; got %v", dumped["service"]["json"], dumped["service"]["yaml"])
+	}
+	if _, ok := dumped["service"]["definitions"]; ok {
+		t.Errorf("want definitions.yaml not to be processed as a schema")
+	}
+}
+
+func TestWalkDuplicates(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		"definitions.json": `{"definitions": {}}`,
+		"definitions.yaml": "definitions: {}\n",
+		"s/m.json":         `{"type": "object", "title": "a long title of the json schema"}`,
+		"s/m.yaml":         "type: string\n",
+	})
+	defer os.RemoveAll(dir)
+	_, err := walkTest(t, New(false), dir)
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("want 2 errors; got %v", err)
+	}
+	for i, names := range [][2]string{
+		{"definitions.yaml", "definitions.json"},
+		{filepath.Join("s", "m.yaml"), filepath.Join("s", "m.json")},
+	} {
+		ferr, ok := errs[i].(*Error)
+		if !ok || ferr.Path != filepath.Join(dir, names[0]) ||
+			!strings.Contains(ferr.Error(), filepath.Join(dir, names[1])) {
+			t.Errorf("want error of %s naming %s; got %v", names[0], names[1], errs[i])
+		}
+	}
+}
+
+func TestStripComments(t *testing.T) {
+	tests := map[string]string{
+		"{\"a\": 1, // comment\n\"b\": [1, 2,],}":       "{\"a\": 1,           \n\"b\": [1, 2 ] }",
+		"/* a\n b */ {\"c\": \"/* x */ // y,]\"}":       "    \n      {\"c\": \"/* x */ // y,]\"}",
+		"{\"d\": \"q\\\"/*\", \"e\": [1 /* , */ , ]\n}": "{\"d\": \"q\\\"/*\", \"e\": [1           ]\n}",
+		"[1, // last\n]": "[1         \n]",
+	}
+	for in, exp := range tests {
+		got, err := stripComments([]byte(in))
+		if err != nil {
+			t.Errorf("%q: want err=nil; got %v", in, err)
+			continue
+		}
+		if string(got) != exp {
+			t.Errorf("%q: want %q; got %q", in, exp, got)
+		}
+	}
+	if _, err := stripComments([]byte("{} /* a")); err == nil {
+		t.Errorf("want err!=nil")
+	}
+}
+
+func TestUnmarshalTolerant(t *testing.T) {
+	data := []byte("{\n  // comment\n  \"a\": [1, 2,],\n}\n")
+	for _, path := range []string{"f.jsonc", "f.json5"} {
+		v, err := New(false).decodeObject(path, data)
+		if err != nil {
+			t.Errorf("%s: want err=nil; got %v", path, err)
+			continue
+		}
+		if exp := []interface{}{1.0, 2.0}; !reflect.DeepEqual(v["a"], exp) {
+			t.Errorf("%s: want a=%v; got %v", path, exp, v["a"])
+		}
+	}
+	if _, err := New(false).decodeObject("f.json", data); err == nil {
+		t.Errorf("want err!=nil")
+	}
+	schg := New(false)
+	schg.Comments = true
+	if _, err := schg.decodeObject("f.json", data); err != nil {
+		t.Errorf("want err=nil; got %v", err)
+	}
+
+	tests := map[string]string{
+		"{\n  /* x */ \"a\": 1\n  \"b\": 2\n}": "f.jsonc:3:3:",
+		"{\n  \"a\": /* x */ ]\n}":             "f.jsonc:2:16:",
+		"{\n  \"a\": 1 /* x\n}":                "f.jsonc:2:10:",
+	}
+	for data, pos := range tests {
+		_, err := schg.decodeObject("f.jsonc", []byte(data))
+		if err == nil || !strings.Contains(err.Error(), pos) {
+			t.Errorf("%q: want err containing %q; got %v", data, pos, err)
+		}
+	}
+}
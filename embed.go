@@ -0,0 +1,76 @@
+package schemagen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// schemaFile is a Go file which holds schemas of a service.
+	schemaFile = `schema.go`
+	// embedDir is a directory of the output package, to which schema files
+	// embedded by `schemaFile` are copied.
+	embedDir = `schemas`
+)
+
+// embedTemplate is a template of `schemaFile` file, which embeds schema files
+// and exposes them with `_bindata` map as bindata does.
+const embedTemplate = `package %s
+
+import "embed"
+
+//go:embed %s
+var _schemas embed.FS
+
+// _bindata maps names of schemas to functions, which return their content.
+var _bindata = map[string]func() ([]byte, error){
+%s}
+`
+
+// EmbedBackend is a Backend, which copies schemas into `embedDir` directory
+// as JSON files and embeds them with go:embed directive in `schemaFile` file.
+// The schemas are bound like the ones of BindataBackend.
+type EmbedBackend struct {
+	BindOptions
+}
+
+// Generate implements Backend interface.
+func (b *EmbedBackend) Generate(serv, dir string, schemas map[string][]byte) error {
+	sdir := filepath.Join(dir, embedDir)
+	if err := os.MkdirAll(sdir, 0755); err != nil {
+		return fileError(sdir, nil, err)
+	}
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var files []string
+	var entries bytes.Buffer
+	for _, name := range names {
+		path := filepath.Join(sdir, name+`.json`)
+		if err := ioutil.WriteFile(path, schemas[name], 0644); err != nil {
+			return fileError(path, nil, err)
+		}
+		// embedded files are named with slash-separated paths.
+		file := embedDir + `/` + name + `.json`
+		files = append(files, fmt.Sprintf("%q", file))
+		fmt.Fprintf(&entries, "%q: func() ([]byte, error) { return _schemas.ReadFile(%q) },\n", name, file)
+	}
+	path := filepath.Join(dir, schemaFile)
+	src, err := format.Source([]byte(fmt.Sprintf(embedTemplate, serv, strings.Join(files, ` `),
+		entries.String())))
+	if err != nil {
+		return newError(path, cannotWriteEmbedErr, err)
+	}
+	if err = ioutil.WriteFile(path, src, 0644); err != nil {
+		return fileError(path, nil, err)
+	}
+	return writeBind(serv, dir, schemas, b.BindOptions)
+}
@@ -0,0 +1,63 @@
+package schemagen
+
+import (
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateEmbed(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		"definitions.json": `{"definitions": {"id": {"type": "integer"}}}`,
+		"users/get.json":   `{"type": "object", "properties": {"id": {"$ref": "#/definitions/id"}}}`,
+		"users/put.yaml":   "type: object\n",
+		"orders/list.json": `{"type": "array"}`,
+	})
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+	g := New(false)
+	g.Embed = true
+	if err := g.Generate(dir, out); err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	cases := map[string][]string{
+		"users":  {"get", "put"},
+		"orders": {"list"},
+	}
+	for serv, methods := range cases {
+		src, err := ioutil.ReadFile(filepath.Join(out, serv, schemaFile))
+		if err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+		if _, err = parser.ParseFile(token.NewFileSet(), schemaFile, src, 0); err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+		if !strings.Contains(string(src), "package "+serv+"\n") {
+			t.Errorf("want package %s; got:\n%s", serv, src)
+		}
+		for _, method := range methods {
+			file := embedDir + "/" + method + ".json"
+			for _, want := range []string{
+				`"` + file + `"`,
+				`"` + method + `": func() ([]byte, error) { return _schemas.ReadFile("` + file + `") },`,
+			} {
+				if !strings.Contains(string(src), want) {
+					t.Errorf("want %s to contain %q; got:\n%s", serv, want, src)
+				}
+			}
+			data, err := ioutil.ReadFile(filepath.Join(out, serv, filepath.FromSlash(file)))
+			if err != nil {
+				t.Fatalf("want err=nil; got %v", err)
+			}
+			var schema map[string]interface{}
+			if err = json.Unmarshal(data, &schema); err != nil {
+				t.Errorf("want err=nil; got %v", err)
+			}
+		}
+	}
+}
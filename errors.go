@@ -0,0 +1,116 @@
+package schemagen
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Error describes a failure of processing a file during Generate's run.
+// Failures of parsing a file are located by line and column.
+type Error struct {
+	// Path is a path of the file.
+	Path string
+	// Line and Column locate the failure in the file, both start at 1.
+	// They are 0 if the position is unknown.
+	Line, Column int
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements error interface.
+func (e *Error) Error() string {
+	switch {
+	case e.Column != 0:
+		return fmt.Sprintf(`schemagen: %s:%d:%d: %v`, e.Path, e.Line, e.Column, e.Err)
+	case e.Line != 0:
+		return fmt.Sprintf(`schemagen: %s:%d: %v`, e.Path, e.Line, e.Err)
+	}
+	return fmt.Sprintf(`schemagen: %s: %v`, e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// newError creates an Error for file located at path with message formatted
+// according to format.
+func newError(path, format string, args ...interface{}) *Error {
+	return &Error{Path: path, Err: fmt.Errorf(format, args...)}
+}
+
+// yamlLine matches line number reported by yaml package.
+var yamlLine = regexp.MustCompile(`line (\d+):`)
+
+// fileError wraps err, which occurred while processing file located at path,
+// into an Error. Position of syntax and type errors is computed from offsets
+// they report in data. Errors which are already wrapped, also the ones
+// collected in Errors, are left untouched.
+func fileError(path string, data []byte, err error) error {
+	var offset int64
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *Error, Errors:
+		return e
+	case *os.PathError:
+		return &Error{Path: e.Path, Err: e.Err}
+	case *json.SyntaxError:
+		offset = e.Offset
+	case *json.UnmarshalTypeError:
+		offset = e.Offset
+	case *commentError:
+		offset = e.offset
+	}
+	ferr := &Error{Path: path, Err: err}
+	if offset != 0 {
+		ferr.Line, ferr.Column = position(data, offset)
+	} else if isYAML(path) {
+		if m := yamlLine.FindStringSubmatch(err.Error()); m != nil {
+			ferr.Line, _ = strconv.Atoi(m[1])
+		}
+	}
+	return ferr
+}
+
+// Errors is a list of failures collected during Generate's or Glob's run,
+// which does not stop at the first one.
+type Errors []error
+
+// Error implements error interface, each failure is written in its own line.
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the collected failures, so errors.Is and errors.As inspect
+// every one of them.
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// add appends err to e, failures of nested Errors are appended one by one.
+func (e *Errors) add(err error) {
+	switch err := err.(type) {
+	case nil:
+	case Errors:
+		*e = append(*e, err...)
+	default:
+		*e = append(*e, err)
+	}
+}
+
+// err returns e or nil if no failures were collected.
+func (e Errors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
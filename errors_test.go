@@ -0,0 +1,170 @@
+package schemagen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestErrorPosition(t *testing.T) {
+	cases := []struct {
+		files        map[string]string
+		path         string
+		line, column int
+	}{
+		{
+			map[string]string{
+				"definitions.json": `{"definitions": {}}`,
+				"s/m.json":         "{\n  \"type\": \"object\",\n  \"x\" 1\n}",
+			},
+			"s/m.json", 3, 7,
+		},
+		{
+			map[string]string{
+				"definitions.json": `{"definitions": {}}`,
+				"s/m.json":         "{\n  \"type\": 1,\n}",
+			},
+			"s/m.json", 3, 1,
+		},
+		{
+			map[string]string{
+				"definitions.json": `{"definitions": {}}`,
+				"s/m.yaml":         "type: object\nproperties:\n\ta: [1, 2]\n",
+			},
+			"s/m.yaml", 3, 0,
+		},
+		{
+			map[string]string{
+				"definitions.json": `{"definitions": {}}`,
+				"s/m.json":         `{"$ref": "b.json"}`,
+				"s/b.json":         "{\n\n  \"type\" \"string\"\n}",
+			},
+			"s/b.json", 3, 10,
+		},
+		{
+			map[string]string{
+				"definitions.json": `{"definitions": {}}`,
+				"s/m.json":         `{"$ref": "#/definitions/missing"}`,
+			},
+			"s/m.json", 0, 0,
+		},
+	}
+	for i, cas := range cases {
+		dir := newSchemaTree(t, cas.files)
+		_, err := walkTest(t, New(false), dir)
+		os.RemoveAll(dir)
+		var ferr *Error
+		if !errors.As(err, &ferr) {
+			t.Fatalf("want *Error (i=%d); got %v", i, err)
+		}
+		if want := filepath.Join(dir, filepath.FromSlash(cas.path)); ferr.Path != want {
+			t.Errorf("want path=%s (i=%d); got %s", want, i, ferr.Path)
+		}
+		if ferr.Line != cas.line || ferr.Column != cas.column {
+			t.Errorf("want position=%d:%d (i=%d); got %d:%d", cas.line, cas.column,
+				i, ferr.Line, ferr.Column)
+		}
+		if !strings.HasPrefix(err.Error(), "schemagen: "+ferr.Path+":") {
+			t.Errorf("want error prefixed with the path (i=%d); got %v", i, err)
+		}
+	}
+}
+
+func TestErrorGenerate(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		"definitions.json": `{"definitions": {"a": {"type": "object"}}}`,
+		"s/m.json":         "{\"type\": \"object\"\n\"x\": 1}",
+	})
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+	err := New(false).Generate(dir, out)
+	var ferr *Error
+	if !errors.As(err, &ferr) {
+		t.Fatalf("want *Error; got %v", err)
+	}
+	if want := filepath.Join(dir, "s", "m.json"); ferr.Path != want || ferr.Line != 2 {
+		t.Errorf("want %s:2; got %s:%d", want, ferr.Path, ferr.Line)
+	}
+	if _, ok := ferr.Err.(*Error); ok {
+		t.Errorf("want unwrapped error; got %v", ferr.Err)
+	}
+}
+
+func TestErrorsGenerate(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		"definitions.json": `{"definitions": {"a": {"type": "object"}}}`,
+		"s/m.json":         `{"type": "object",}`,
+		"s/n.json":         `{"$ref": "#/definitions/b"}`,
+		"t/m.json":         `{"$ref": "#/definitions/a"}`,
+		"t/n.yaml":         "- type: object\n",
+	})
+	defer os.RemoveAll(dir)
+	err := New(false).Generate(dir, filepath.Join(dir, "out"))
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("want Errors; got %v", err)
+	}
+	paths := make(map[string]bool)
+	for _, err := range errs {
+		if ferr, ok := err.(*Error); ok {
+			paths[ferr.Path] = true
+		}
+	}
+	for _, name := range []string{"s/m.json", "s/n.json", "t/n.yaml"} {
+		if path := filepath.Join(dir, filepath.FromSlash(name)); !paths[path] {
+			t.Errorf("want error for %s; got %v", path, err)
+		}
+	}
+	if len(errs) != 3 {
+		t.Errorf("want len(errs)=3; got %d", len(errs))
+	}
+	var ferr *Error
+	if !errors.As(err, &ferr) {
+		t.Errorf("want *Error; got %v", err)
+	}
+}
+
+func TestErrorsDefinitions(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		"definitions.json": "{\"definitions\": {\n\"a\": {\"type\": \"object\"},}}",
+		"s/m.json":         `{"type": "object"}`,
+		"s/n.json":         `{"$ref": "#/definitions/a"}`,
+	})
+	defer os.RemoveAll(dir)
+	err := New(false).Generate(dir, filepath.Join(dir, "out"))
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("want Errors; got %v", err)
+	}
+	// s/n.json misses definitions only because definitions.json is invalid.
+	if len(errs) != 1 {
+		t.Errorf("want len(errs)=1; got %v", errs)
+	}
+	var ferr *Error
+	if !errors.As(errs[0], &ferr) {
+		t.Fatalf("want *Error; got %v", errs[0])
+	}
+	if want := filepath.Join(dir, "definitions.json"); ferr.Path != want || ferr.Line != 2 {
+		t.Errorf("want %s:2; got %s:%d", want, ferr.Path, ferr.Line)
+	}
+}
+
+func TestGenerateNoDefinitions(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		"svc/m.json": `{"type": "object"}`,
+		"svc/n.json": `{"properties": {"a": {"$ref": "#/definitions/a"}},
+			"definitions": {"a": {"type": "string"}}}`,
+		"svc/o.json": `{"$ref": "#/definitions/id"}`,
+	})
+	defer os.RemoveAll(dir)
+	err := New(false).Generate(dir, filepath.Join(dir, "out"))
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("want only o.json to fail; got %v", err)
+	}
+	if ferr, ok := errs[0].(*Error); !ok || ferr.Path != filepath.Join(dir, "svc", "o.json") {
+		t.Errorf("want error of o.json; got %v", errs[0])
+	}
+}
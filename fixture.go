@@ -0,0 +1,94 @@
+package schemagen
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+const (
+	// validSuffix is a suffix of directories with sample documents, which
+	// are expected to match the schema named by the rest of directory's name.
+	validSuffix = `.valid`
+	// invalidSuffix is a suffix of directories with sample documents, which
+	// are expected not to match the schema.
+	invalidSuffix = `.invalid`
+)
+
+// isFixturesDir reports whether directory located at path holds sample
+// documents.
+func isFixturesDir(path string) bool {
+	return strings.HasSuffix(path, validSuffix) || strings.HasSuffix(path, invalidSuffix)
+}
+
+// Test validates sample documents found in schemaInBase directory tree against
+// the schemas they are kept next to. Documents of `method.valid` directory are
+// expected to match `method` schema merged with definitions, the ones of
+// `method.invalid` directory are expected not to match it. Invalid schemas and
+// documents which do not meet expectations are returned as Errors.
+func (s *schg) Test(schemaInBase string) (err error) {
+	s.definitions = nil
+	if schemaInBase, err = filepath.Abs(filepath.Clean(schemaInBase)); err != nil {
+		return
+	}
+	if err = s.walk(schemaInBase); err != nil {
+		return
+	}
+	var errs Errors
+	sort.Strings(s.fixtures)
+	for _, dir := range s.fixtures {
+		errs.add(s.testFixtures(dir))
+	}
+	return errs.err()
+}
+
+// testFixtures validates sample documents of dir against the schema which
+// dir's name refers to.
+func (s *schg) testFixtures(dir string) error {
+	valid := strings.HasSuffix(dir, validSuffix)
+	name := strings.TrimSuffix(strings.TrimSuffix(dir, validSuffix), invalidSuffix)
+	schema, ok := s.merged[name]
+	if !ok {
+		return newError(dir, noFixtureSchemaErr, filepath.Base(name))
+	}
+	sch, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(schema))
+	if err != nil {
+		return newError(dir, invalidFixtureSchemaErr, filepath.Base(name), err)
+	}
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fileError(dir, nil, err)
+	}
+	var errs Errors
+	for _, fi := range fis {
+		if fi.IsDir() || !schemaExts[filepath.Ext(fi.Name())] {
+			continue
+		}
+		path := filepath.Join(dir, fi.Name())
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			errs.add(fileError(path, nil, err))
+			continue
+		}
+		doc, err := s.decode(path, data)
+		if err != nil {
+			errs.add(err)
+			continue
+		}
+		res, err := sch.Validate(gojsonschema.NewGoLoader(doc))
+		switch {
+		case err != nil:
+			errs.add(newError(path, invalidFixtureSchemaErr, filepath.Base(name), err))
+		case valid && !res.Valid():
+			for _, e := range res.Errors() {
+				errs.add(newError(path, fixtureMismatchErr, filepath.Base(name), e))
+			}
+		case !valid && res.Valid():
+			errs.add(newError(path, fixtureMatchErr, filepath.Base(name)))
+		}
+	}
+	return errs.err()
+}
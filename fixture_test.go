@@ -0,0 +1,68 @@
+package schemagen
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestTest(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		"definitions.json": `{"definitions": {"n": {"type": "integer"}}}`,
+		"s/m.json": `{"type": "object", "required": ["a"],
+			"properties": {"a": {"$ref": "#/definitions/n"}}}`,
+		"s/m.valid/ok.json":     `{"a": 1}`,
+		"s/m.valid/bad.json":    `{"a": "x"}`,
+		"s/m.valid/ok.yaml":     "a: 2\n",
+		"s/m.valid/readme.txt":  `{"a": "x"}`,
+		"s/m.invalid/ok.json":   `{}`,
+		"s/m.invalid/bad.json":  `{"a": 3}`,
+		"s/n.yaml":              "type: string\n",
+		"s/n.invalid/ok.json":   `1`,
+		"s/x.valid/a.json":      `{}`,
+		"t/m.jsonc":             `{"type": "array"} // comment`,
+		"t/m.valid/empty.jsonc": `[] // comment`,
+	})
+	defer os.RemoveAll(dir)
+	err := New(false).Test(dir)
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("want Errors; got %v", err)
+	}
+	var paths []string
+	for _, err := range errs {
+		ferr, ok := err.(*Error)
+		if !ok {
+			t.Fatalf("want *Error; got %v", err)
+		}
+		rel, err := filepath.Rel(dir, ferr.Path)
+		if err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	sort.Strings(paths)
+	want := []string{"s/m.invalid/bad.json", "s/m.valid/bad.json", "s/x.valid"}
+	if len(paths) != len(want) {
+		t.Fatalf("want paths=%v; got %v", want, paths)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("want paths[%d]=%s; got %s", i, want[i], paths[i])
+		}
+	}
+}
+
+func TestTestValid(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		"definitions.json":   `{"definitions": {"n": {"type": "integer"}}}`,
+		"s/m.json":           `{"$ref": "#/definitions/n"}`,
+		"s/m.valid/a.json":   `1`,
+		"s/m.invalid/a.json": `"1"`,
+	})
+	defer os.RemoveAll(dir)
+	if err := New(false).Test(dir); err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+}
@@ -0,0 +1,675 @@
+package schemagen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// nativeFile is a Go file to which generated validation code is stored.
+const nativeFile = `native.go`
+
+// nativeKeywords are keywords, which are translated into Go code.
+var nativeKeywords = map[string]bool{`$ref`: true, `type`: true, `enum`: true,
+	`const`: true, `minimum`: true, `maximum`: true, `exclusiveMinimum`: true,
+	`exclusiveMaximum`: true, `multipleOf`: true, `minLength`: true, `maxLength`: true,
+	`pattern`: true, `properties`: true, `required`: true, `additionalProperties`: true,
+	`minProperties`: true, `maxProperties`: true, `items`: true, `additionalItems`: true,
+	`minItems`: true, `maxItems`: true, `allOf`: true, `anyOf`: true, `oneOf`: true,
+	`not`: true}
+
+// annotationKeywords are keywords, which do not take part in validation.
+var annotationKeywords = map[string]bool{`$schema`: true, `id`: true, `$id`: true,
+	`title`: true, `description`: true, `default`: true, `examples`: true,
+	`definitions`: true, `$defs`: true, `$comment`: true, `readOnly`: true,
+	`writeOnly`: true}
+
+// nativeUnsupported returns keywords of schema, which cannot be translated
+// into Go code, along with pointers to subschemas which use them.
+func nativeUnsupported(schema map[string]interface{}) (unsupported []string) {
+	walkSchemas(schema, ``, func(ptr string, sub map[string]interface{}) {
+		for key, cont := range sub {
+			ok := nativeKeywords[key] || annotationKeywords[key]
+			switch key {
+			case `$ref`:
+				ref, _ := cont.(string)
+				ok = strings.HasPrefix(ref, `#`)
+			case `pattern`:
+				pattern, _ := cont.(string)
+				_, err := regexp.Compile(pattern)
+				ok = err == nil
+			}
+			if !ok {
+				unsupported = append(unsupported, key+` at #`+ptr)
+			}
+		}
+	})
+	sort.Strings(unsupported)
+	return
+}
+
+// nativeFunc is a validation function pending generation.
+type nativeFunc struct {
+	name   string
+	schema interface{}
+}
+
+// nativeGen generates Go validation code of schemas which belong to one
+// package. Every subschema referred by `$ref` or being an alternative of
+// anyOf, oneOf or not keyword is validated by its own function.
+type nativeGen struct {
+	// funcs contains generated functions and vars package-level variables.
+	funcs, vars bytes.Buffer
+	// w is a buffer to which statements are written.
+	w *bytes.Buffer
+	// validators maps names of schemas to their entry functions.
+	validators map[string]string
+	// used contains names of generated functions and variables.
+	used map[string]bool
+	// root is a schema which code is being generated.
+	root map[string]interface{}
+	// prefix is a prefix of names of root's functions.
+	prefix string
+	// refs maps references of root to functions which validate them.
+	refs map[string]string
+	// queue contains functions of root pending generation.
+	queue []nativeFunc
+	// n is a counter of local variables.
+	n int
+}
+
+// nativeNames are package-level identifiers declared by `nativeHelpers` and
+// by `nativeFile` file, which generated functions must not collide with.
+var nativeNames = []string{`nativeCount`, `nativeEqual`, `nativeError`, `nativeIn`,
+	`nativeIs`, `nativeJSON`, `nativeMultiple`, `nativeValidate`, `nativeValidators`}
+
+// newNativeGen creates empty nativeGen.
+func newNativeGen() *nativeGen {
+	g := &nativeGen{validators: make(map[string]string), used: make(map[string]bool)}
+	for _, name := range nativeNames {
+		g.used[name] = true
+	}
+	return g
+}
+
+// name returns unique identifier derived from base.
+func (g *nativeGen) name(base string) string {
+	name := base
+	for i := 2; g.used[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	g.used[name] = true
+	return name
+}
+
+// capture returns statements written by fn.
+func (g *nativeGen) capture(fn func()) string {
+	var b bytes.Buffer
+	w := g.w
+	g.w = &b
+	fn()
+	g.w = w
+	return b.String()
+}
+
+// local returns unique name of local variable.
+func (g *nativeGen) local() string {
+	g.n++
+	return fmt.Sprintf("v%d", g.n)
+}
+
+// addSchema generates validation code of schema read from file named method.
+// Schemas which use keywords not supported by the generator are not
+// translated, their unsupported keywords are returned instead.
+func (g *nativeGen) addSchema(method string, schema map[string]interface{}) []string {
+	if unsupported := nativeUnsupported(schema); len(unsupported) != 0 {
+		return unsupported
+	}
+	g.root, g.refs, g.queue = schema, make(map[string]string), nil
+	g.prefix = `native` + goName(method)
+	entry := g.name(g.prefix)
+	g.refs[`#`] = entry
+	g.queue = append(g.queue, nativeFunc{name: entry, schema: schema})
+	for len(g.queue) != 0 {
+		fn := g.queue[0]
+		g.queue = g.queue[1:]
+		g.n = 0
+		body := g.capture(func() { g.emit(fn.schema, `v`, `path`) })
+		fmt.Fprintf(&g.funcs, "\nfunc %s(v interface{}, path string) error {\n%sreturn nil\n}\n",
+			fn.name, body)
+	}
+	g.validators[method] = entry
+	return nil
+}
+
+// refFunc returns name of function which validates value against subschema
+// pointed by local reference ref.
+func (g *nativeGen) refFunc(ref string) string {
+	if name, ok := g.refs[ref]; ok {
+		return name
+	}
+	_, frag := splitRef(ref)
+	toks, _ := pointerTokens(frag)
+	target, _ := resolvePointer(g.root, toks)
+	suffix := `Ref`
+	if len(toks) == 2 && isDefinitionsKey(toks[0]) {
+		suffix = goName(toks[1])
+	}
+	name := g.name(g.prefix + suffix)
+	g.refs[ref] = name
+	g.queue = append(g.queue, nativeFunc{name: name, schema: target})
+	return name
+}
+
+// subFunc returns name of function which validates value against subschema v.
+func (g *nativeGen) subFunc(v interface{}) string {
+	name := g.name(g.prefix + `Sub`)
+	g.queue = append(g.queue, nativeFunc{name: name, schema: v})
+	return name
+}
+
+// emit writes statements, which validate value of variable x against schema
+// v. The statements return error which refers to path expression p.
+func (g *nativeGen) emit(v interface{}, x, p string) {
+	w := g.w
+	schema, ok := v.(map[string]interface{})
+	if !ok {
+		// boolean schemas of draft-06 and newer.
+		if b, ok := v.(bool); ok && !b {
+			fmt.Fprintf(w, "return nativeError(%s, \"no value is allowed\")\n", p)
+		}
+		return
+	}
+	if ref, ok := schema[`$ref`].(string); ok {
+		// keywords next to reference are ignored.
+		fmt.Fprintf(w, "if err := %s(%s, %s); err != nil {\nreturn err\n}\n", g.refFunc(ref), x, p)
+		return
+	}
+	g.emitType(schema, x, p)
+	if enum, ok := schema[`enum`].([]interface{}); ok {
+		name := g.name(`nativeEnum`)
+		fmt.Fprintf(&g.vars, "var %s = %s\n", name, goLiteral(enum))
+		fmt.Fprintf(w, "if !nativeIn(%s, %s) {\nreturn nativeError(%s, \"value is not one of enumerated ones\")\n}\n",
+			x, name, p)
+	}
+	if c, ok := schema[`const`]; ok {
+		fmt.Fprintf(w, "if !nativeEqual(%s, %s) {\nreturn nativeError(%s, \"value is not equal to constant\")\n}\n",
+			x, goLiteral(c), p)
+	}
+	g.emitNumber(schema, x, p)
+	g.emitString(schema, x, p)
+	g.emitObject(schema, x, p)
+	g.emitArray(schema, x, p)
+	if all, ok := schema[`allOf`].([]interface{}); ok {
+		for _, sub := range all {
+			g.emit(sub, x, p)
+		}
+	}
+	if any, ok := schema[`anyOf`].([]interface{}); ok && len(any) != 0 {
+		conds := make([]string, len(any))
+		for i, sub := range any {
+			conds[i] = fmt.Sprintf("%s(%s, %s) != nil", g.subFunc(sub), x, p)
+		}
+		fmt.Fprintf(w, "if %s {\nreturn nativeError(%s, \"value does not match any schema of anyOf\")\n}\n",
+			strings.Join(conds, " && "), p)
+	}
+	if one, ok := schema[`oneOf`].([]interface{}); ok && len(one) != 0 {
+		conds := make([]string, len(one))
+		for i, sub := range one {
+			conds[i] = fmt.Sprintf("%s(%s, %s) == nil", g.subFunc(sub), x, p)
+		}
+		fmt.Fprintf(w, "if nativeCount(%s) != 1 {\nreturn nativeError(%s, \"value does not match exactly one schema of oneOf\")\n}\n",
+			strings.Join(conds, ", "), p)
+	}
+	if not, ok := schema[`not`]; ok {
+		fmt.Fprintf(w, "if %s(%s, %s) == nil {\nreturn nativeError(%s, \"value matches schema of not\")\n}\n",
+			g.subFunc(not), x, p, p)
+	}
+}
+
+// emitType writes statements validating type of x.
+func (g *nativeGen) emitType(schema map[string]interface{}, x, p string) {
+	var types []string
+	switch typ := schema[`type`].(type) {
+	case string:
+		types = []string{typ}
+	case []interface{}:
+		for _, t := range typ {
+			if t, ok := t.(string); ok {
+				types = append(types, t)
+			}
+		}
+	}
+	if len(types) == 0 {
+		return
+	}
+	conds := make([]string, len(types))
+	for i, t := range types {
+		conds[i] = fmt.Sprintf("!nativeIs(%s, %q)", x, t)
+	}
+	fmt.Fprintf(g.w, "if %s {\nreturn nativeError(%s, \"value is not of type %s\")\n}\n",
+		strings.Join(conds, " && "), p, strings.Join(types, ", "))
+}
+
+// emitNumber writes statements validating numeric x.
+func (g *nativeGen) emitNumber(schema map[string]interface{}, x, p string) {
+	var b bytes.Buffer
+	n := g.local()
+	bound := func(key, op, exclusive, exop string) {
+		limit, ok := schema[key].(float64)
+		if !ok {
+			return
+		}
+		if excl, _ := schema[exclusive].(bool); excl {
+			op = exop
+		}
+		fmt.Fprintf(&b, "if %s %s %s {\nreturn nativeError(%s, \"value must be %s %s\")\n}\n",
+			n, op, goFloat(limit), p, negate[op], goFloat(limit))
+	}
+	bound(`minimum`, `<`, `exclusiveMinimum`, `<=`)
+	bound(`maximum`, `>`, `exclusiveMaximum`, `>=`)
+	// exclusive bounds of draft-06 and newer are numbers.
+	if limit, ok := schema[`exclusiveMinimum`].(float64); ok {
+		fmt.Fprintf(&b, "if %s <= %s {\nreturn nativeError(%s, \"value must be > %s\")\n}\n",
+			n, goFloat(limit), p, goFloat(limit))
+	}
+	if limit, ok := schema[`exclusiveMaximum`].(float64); ok {
+		fmt.Fprintf(&b, "if %s >= %s {\nreturn nativeError(%s, \"value must be < %s\")\n}\n",
+			n, goFloat(limit), p, goFloat(limit))
+	}
+	// multiples are computed with decimals, which numbers are written in,
+	// like the interpreter does, so 0.3 is a multiple of 0.1.
+	if mul, ok := schema[`multipleOf`].(float64); ok {
+		fmt.Fprintf(&b, "if !nativeMultiple(%s, %s) {\nreturn nativeError(%s, \"value must be a multiple of %s\")\n}\n",
+			n, goFloat(mul), p, goFloat(mul))
+	}
+	if b.Len() != 0 {
+		fmt.Fprintf(g.w, "if %s, ok := %s.(float64); ok {\n%s}\n", n, x, b.String())
+	}
+}
+
+// negate maps comparison operators, which fail validation, to the ones
+// describing valid values.
+var negate = map[string]string{`<`: `>=`, `<=`: `>`, `>`: `<=`, `>=`: `<`}
+
+// emitString writes statements validating string x.
+func (g *nativeGen) emitString(schema map[string]interface{}, x, p string) {
+	var b bytes.Buffer
+	s := g.local()
+	if min, ok := schema[`minLength`].(float64); ok {
+		fmt.Fprintf(&b, "if utf8.RuneCountInString(%s) < %s {\nreturn nativeError(%s, \"string must be at least %s characters long\")\n}\n",
+			s, goFloat(min), p, goFloat(min))
+	}
+	if max, ok := schema[`maxLength`].(float64); ok {
+		fmt.Fprintf(&b, "if utf8.RuneCountInString(%s) > %s {\nreturn nativeError(%s, \"string must be at most %s characters long\")\n}\n",
+			s, goFloat(max), p, goFloat(max))
+	}
+	if pattern, ok := schema[`pattern`].(string); ok {
+		name := g.name(`nativePattern`)
+		fmt.Fprintf(&g.vars, "var %s = regexp.MustCompile(%q)\n", name, pattern)
+		fmt.Fprintf(&b, "if !%s.MatchString(%s) {\nreturn nativeError(%s, %q)\n}\n",
+			name, s, p, "string does not match pattern "+pattern)
+	}
+	if b.Len() != 0 {
+		fmt.Fprintf(g.w, "if %s, ok := %s.(string); ok {\n%s}\n", s, x, b.String())
+	}
+}
+
+// emitObject writes statements validating object x.
+func (g *nativeGen) emitObject(schema map[string]interface{}, x, p string) {
+	m := g.local()
+	body := g.capture(func() {
+		w := g.w
+		if min, ok := schema[`minProperties`].(float64); ok {
+			fmt.Fprintf(w, "if len(%s) < %s {\nreturn nativeError(%s, \"object must have at least %s properties\")\n}\n",
+				m, goFloat(min), p, goFloat(min))
+		}
+		if max, ok := schema[`maxProperties`].(float64); ok {
+			fmt.Fprintf(w, "if len(%s) > %s {\nreturn nativeError(%s, \"object must have at most %s properties\")\n}\n",
+				m, goFloat(max), p, goFloat(max))
+		}
+		req, _ := schema[`required`].([]interface{})
+		for _, name := range req {
+			if name, ok := name.(string); ok {
+				fmt.Fprintf(w, "if _, ok := %s[%q]; !ok {\nreturn nativeError(%s, %q)\n}\n",
+					m, name, p, "property "+name+" is required")
+			}
+		}
+		props, _ := schema[`properties`].(map[string]interface{})
+		names := make([]string, 0, len(props))
+		for name := range props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			val := g.local()
+			prop := g.capture(func() {
+				g.emit(props[name], val, p+`+`+strconv.Quote(`/`+escapeToken(name)))
+			})
+			if prop != "" {
+				fmt.Fprintf(w, "if %s, ok := %s[%q]; ok {\n%s}\n", val, m, name, prop)
+			}
+		}
+		// known properties are skipped by additionalProperties.
+		var known string
+		if len(names) != 0 {
+			quoted := make([]string, len(names))
+			for i, name := range names {
+				quoted[i] = strconv.Quote(name)
+			}
+			known = strings.Join(quoted, ", ")
+		}
+		key, val := g.local(), g.local()
+		switch ap := schema[`additionalProperties`].(type) {
+		case bool:
+			if ap {
+				break
+			}
+			fmt.Fprintf(w, "for %s := range %s {\n", key, m)
+			if known != "" {
+				fmt.Fprintf(w, "switch %s {\ncase %s:\ncontinue\n}\n", key, known)
+			}
+			fmt.Fprintf(w, "return nativeError(%s, \"additional property %%s is not allowed\", %s)\n}\n", p, key)
+		case map[string]interface{}:
+			sub := g.capture(func() { g.emit(ap, val, p+`+"/"+`+key) })
+			if sub == "" {
+				break
+			}
+			fmt.Fprintf(w, "for %s, %s := range %s {\n", key, val, m)
+			if known != "" {
+				fmt.Fprintf(w, "switch %s {\ncase %s:\ncontinue\n}\n", key, known)
+			}
+			fmt.Fprintf(w, "%s}\n", sub)
+		}
+	})
+	if body != "" {
+		fmt.Fprintf(g.w, "if %s, ok := %s.(map[string]interface{}); ok {\n%s}\n", m, x, body)
+	}
+}
+
+// emitArray writes statements validating array x.
+func (g *nativeGen) emitArray(schema map[string]interface{}, x, p string) {
+	a := g.local()
+	// rest validates items starting at index from against sub.
+	rest := func(sub interface{}, from int) {
+		i, val := g.local(), g.local()
+		skip := ""
+		if from != 0 {
+			skip = fmt.Sprintf("if %s < %d {\ncontinue\n}\n", i, from)
+		}
+		if b, ok := sub.(bool); ok && !b {
+			fmt.Fprintf(g.w, "for %s := range %s {\n%sreturn nativeError(%s+\"/\"+strconv.Itoa(%s), \"additional item is not allowed\")\n}\n",
+				i, a, skip, p, i)
+			return
+		}
+		body := g.capture(func() { g.emit(sub, val, p+`+"/"+strconv.Itoa(`+i+`)`) })
+		if body != "" {
+			fmt.Fprintf(g.w, "for %s, %s := range %s {\n%s%s}\n", i, val, a, skip, body)
+		}
+	}
+	body := g.capture(func() {
+		w := g.w
+		if min, ok := schema[`minItems`].(float64); ok {
+			fmt.Fprintf(w, "if len(%s) < %s {\nreturn nativeError(%s, \"array must have at least %s items\")\n}\n",
+				a, goFloat(min), p, goFloat(min))
+		}
+		if max, ok := schema[`maxItems`].(float64); ok {
+			fmt.Fprintf(w, "if len(%s) > %s {\nreturn nativeError(%s, \"array must have at most %s items\")\n}\n",
+				a, goFloat(max), p, goFloat(max))
+		}
+		switch items := schema[`items`].(type) {
+		case map[string]interface{}, bool:
+			rest(items, 0)
+		case []interface{}:
+			for i, sub := range items {
+				item := g.capture(func() {
+					g.emit(sub, fmt.Sprintf("%s[%d]", a, i), p+`+`+strconv.Quote(`/`+strconv.Itoa(i)))
+				})
+				if item != "" {
+					fmt.Fprintf(w, "if len(%s) > %d {\n%s}\n", a, i, item)
+				}
+			}
+			if additional, ok := schema[`additionalItems`]; ok {
+				rest(additional, len(items))
+			}
+		}
+	})
+	if body != "" {
+		fmt.Fprintf(g.w, "if %s, ok := %s.([]interface{}); ok {\n%s}\n", a, x, body)
+	}
+}
+
+// goFloat returns Go literal of number f.
+func goFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// goLiteral returns Go expression which evaluates to JSON value v, as it
+// would be unmarshaled by encoding/json.
+func goLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		elems := make([]string, len(names))
+		for i, name := range names {
+			elems[i] = strconv.Quote(name) + ": " + goLiteral(v[name])
+		}
+		return "map[string]interface{}{" + strings.Join(elems, ", ") + "}"
+	case []interface{}:
+		elems := make([]string, len(v))
+		for i, cont := range v {
+			elems[i] = goLiteral(cont)
+		}
+		return "[]interface{}{" + strings.Join(elems, ", ") + "}"
+	case string:
+		return strconv.Quote(v)
+	case float64:
+		return "float64(" + goFloat(v) + ")"
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return "nil"
+}
+
+// nativeHelpers are functions used by generated validation code.
+const nativeHelpers = `
+func nativeValidate(doc interface{}, fn func(interface{}, string) error) error {
+	// values which are not decoded from JSON, e.g. structs, are converted
+	// to the decoded ones, like the interpreter does.
+	if !nativeJSON(doc) {
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return nativeError("", "%v", err)
+		}
+		var v interface{}
+		if err = json.Unmarshal(data, &v); err != nil {
+			return nativeError("", "%v", err)
+		}
+		doc = v
+	}
+	return fn(doc, "")
+}
+
+func nativeJSON(v interface{}) bool {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, w := range v {
+			if !nativeJSON(w) {
+				return false
+			}
+		}
+	case []interface{}:
+		for _, w := range v {
+			if !nativeJSON(w) {
+				return false
+			}
+		}
+	case string, float64, bool, nil:
+	default:
+		return false
+	}
+	return true
+}
+
+func nativeError(path, format string, args ...interface{}) error {
+	if path == "" {
+		path = "(root)"
+	}
+	return fmt.Errorf("%s: "+format, append([]interface{}{path}, args...)...)
+}
+
+func nativeIs(v interface{}, typ string) bool {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return typ == "object"
+	case []interface{}:
+		return typ == "array"
+	case string:
+		return typ == "string"
+	case float64:
+		return typ == "number" || (typ == "integer" && v == math.Trunc(v))
+	case bool:
+		return typ == "boolean"
+	case nil:
+		return typ == "null"
+	}
+	return false
+}
+
+func nativeEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		m, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(m) {
+			return false
+		}
+		for k, v := range a {
+			if w, ok := m[k]; !ok || !nativeEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		s, ok := b.([]interface{})
+		if !ok || len(a) != len(s) {
+			return false
+		}
+		for i := range a {
+			if !nativeEqual(a[i], s[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func nativeIn(v interface{}, values []interface{}) bool {
+	for _, w := range values {
+		if nativeEqual(v, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func nativeMultiple(v, mul float64) bool {
+	x, _ := new(big.Rat).SetString(strconv.FormatFloat(v, 'g', -1, 64))
+	m, _ := new(big.Rat).SetString(strconv.FormatFloat(mul, 'g', -1, 64))
+	return m.Sign() != 0 && new(big.Rat).Quo(x, m).IsInt()
+}
+
+func nativeCount(oks ...bool) (n int) {
+	for _, ok := range oks {
+		if ok {
+			n++
+		}
+	}
+	return
+}
+`
+
+// source returns formatted Go source file of package pkg with generated
+// validation code.
+func (g *nativeGen) source(pkg string) ([]byte, error) {
+	var body bytes.Buffer
+	methods := make([]string, 0, len(g.validators))
+	for method := range g.validators {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	body.WriteString("\n// nativeValidators maps names of schemas to functions generated for them.\n")
+	body.WriteString("var nativeValidators = map[string]func(interface{}) error{\n")
+	for _, method := range methods {
+		fmt.Fprintf(&body, "%q: func(v interface{}) error { return nativeValidate(v, %s) },\n",
+			method, g.validators[method])
+	}
+	body.WriteString("}\n\n")
+	body.Write(g.vars.Bytes())
+	body.Write(g.funcs.Bytes())
+	body.WriteString(nativeHelpers)
+	imports := []string{`encoding/json`, `fmt`, `math`, `math/big`, `strconv`}
+	for _, pkg := range []string{`regexp`, `unicode/utf8`} {
+		if bytes.Contains(body.Bytes(), []byte(filepath.Base(pkg)+`.`)) {
+			imports = append(imports, pkg)
+		}
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "package %s\n\nimport (\n", pkg)
+	for _, imp := range imports {
+		fmt.Fprintf(&buf, "%q\n", imp)
+	}
+	buf.WriteString(")\n")
+	buf.Write(body.Bytes())
+	return format.Source(buf.Bytes())
+}
+
+// createNativeFiles makes `nativeFile` file for each service, which contains
+// Go code validating documents against service's schemas. Schemas which use
+// keywords not supported by the generator are validated by the interpreter,
+// the keywords are logged.
+func (s *schg) createNativeFiles(schemaOutBase string) error {
+	gens := make(map[string]*nativeGen)
+	paths := make([]string, 0, len(s.merged))
+	for path := range s.merged {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		serv := s.service(path)
+		if gens[serv] == nil {
+			gens[serv] = newNativeGen()
+		}
+		if unsupported := gens[serv].addSchema(filepath.Base(path), s.merged[path]); len(unsupported) != 0 {
+			log.Println(fmt.Sprintf(nativeFallbackErr, path, strings.Join(unsupported, `, `)))
+		}
+	}
+	var errs Errors
+	for serv, g := range gens {
+		subdir := serv
+		if s.merge || serv == filepath.Base(schemaOutBase) {
+			subdir = ""
+		}
+		name := filepath.Join(schemaOutBase, subdir, nativeFile)
+		src, err := g.source(serv)
+		if err != nil {
+			errs.add(newError(name, cannotWriteNativeErr, err))
+			continue
+		}
+		if err = ioutil.WriteFile(name, src, 0644); err != nil {
+			errs.add(fileError(name, nil, err))
+		}
+	}
+	return errs.err()
+}
@@ -0,0 +1,274 @@
+package schemagen
+
+import (
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestNativeUnsupported(t *testing.T) {
+	cases := []struct {
+		schema string
+		want   []string
+	}{
+		{`{"type": "object", "title": "t", "properties": {"a": {"minLength": 1}}}`, nil},
+		{`{"type": "object", "properties": {"a": {"format": "email"}},
+			"patternProperties": {"^x": {"uniqueItems": true}}}`,
+			[]string{"format at #/properties/a", "patternProperties at #",
+				"uniqueItems at #/patternProperties/^x"}},
+		{`{"properties": {"a": {"pattern": "(?=x)"}}}`, []string{"pattern at #/properties/a"}},
+		{`{"items": {"$ref": "other.json#/a"}}`, []string{"$ref at #/items"}},
+	}
+	for i, cas := range cases {
+		var schema map[string]interface{}
+		if err := json.Unmarshal([]byte(cas.schema), &schema); err != nil {
+			t.Fatalf("want err=nil (i=%d); got %v", i, err)
+		}
+		if got := nativeUnsupported(schema); !reflect.DeepEqual(got, cas.want) {
+			t.Errorf("want unsupported=%v (i=%d); got %v", cas.want, i, got)
+		}
+	}
+}
+
+func TestNativeGen(t *testing.T) {
+	schemas := map[string]string{
+		"create_user": `{
+			"type": "object",
+			"required": ["name"],
+			"properties": {
+				"name": {"type": "string", "minLength": 1, "pattern": "^[a-z]+$"},
+				"age": {"type": "integer", "minimum": 0},
+				"address": {"$ref": "#/definitions/address"},
+				"tags": {"type": "array", "items": {"enum": ["a", "b"]}, "maxItems": 2}
+			},
+			"additionalProperties": false,
+			"definitions": {
+				"address": {"oneOf": [{"type": "string"}, {"$ref": "#/definitions/address"}]}
+			}
+		}`,
+		"get_user": `{"type": "object", "properties": {"id": {"format": "uuid"}}}`,
+	}
+	g := newNativeGen()
+	for _, method := range []string{"create_user", "get_user"} {
+		var schema map[string]interface{}
+		if err := json.Unmarshal([]byte(schemas[method]), &schema); err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+		unsupported := g.addSchema(method, schema)
+		if (method == "get_user") != (len(unsupported) != 0) {
+			t.Errorf("want %s to be translated only if supported; got %v", method, unsupported)
+		}
+	}
+	src, err := g.source("users")
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	if _, err = parser.ParseFile(token.NewFileSet(), nativeFile, src, 0); err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	for _, want := range []string{
+		`"create_user": func(v interface{}) error { return nativeValidate(v, nativeCreateUser) },`,
+		`var nativePattern = regexp.MustCompile("^[a-z]+$")`,
+		`var nativeEnum = []interface{}{"a", "b"}`,
+		"func nativeCreateUserAddress(v interface{}, path string) error {",
+		`return nativeError(path, "property name is required")`,
+		`if err := nativeCreateUserAddress(v`,
+		`if nativeCount(nativeCreateUserSub(v, path) == nil, nativeCreateUserSub2(v, path) == nil) != 1 {`,
+		`if err := nativeCreateUserAddress(v, path); err != nil {`,
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("want source to contain %q; got:\n%s", want, src)
+		}
+	}
+	if strings.Contains(string(src), "nativeGetUser") {
+		t.Errorf("want get_user not to be translated; got:\n%s", src)
+	}
+}
+
+func TestGenerateNative(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		"definitions.json": `{"definitions": {"id": {"type": "integer"}}}`,
+		"users/get.json":   `{"type": "object", "properties": {"id": {"$ref": "#/definitions/id"}}}`,
+	})
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+	g := New(false)
+	g.Native = true
+	if err := g.Generate(dir, out); err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	for file, want := range map[string]string{
+		nativeFile: `"get": func(v interface{}) error { return nativeValidate(v, nativeGet) },`,
+		outputFile: "if fn, ok := nativeValidators[name]; ok {",
+	} {
+		src, err := ioutil.ReadFile(filepath.Join(out, "users", file))
+		if err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+		if !strings.Contains(string(src), want) {
+			t.Errorf("want %s to contain %q; got:\n%s", file, want, src)
+		}
+	}
+}
+
+// runNative builds package of files, which are named after their keys, and
+// returns output of its main function. The test is skipped if Go toolchain
+// is not available.
+func runNative(t *testing.T, files map[string][]byte) string {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skipf("go toolchain is not available: %v", err)
+	}
+	dir, err := ioutil.TempDir(os.TempDir(), "native")
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	files["go.mod"] = []byte("module native\n")
+	for name, data := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+	}
+	cmd := exec.Command(gobin, "run", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("want err=nil; got %v:\n%s", err, out)
+	}
+	return string(out)
+}
+
+// nativeSource returns native code of package main generated for schemas.
+func nativeSource(t *testing.T, schemas map[string]string) []byte {
+	g := newNativeGen()
+	methods := make([]string, 0, len(schemas))
+	for method := range schemas {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		var schema map[string]interface{}
+		if err := json.Unmarshal([]byte(schemas[method]), &schema); err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+		if unsupported := g.addSchema(method, schema); len(unsupported) != 0 {
+			t.Fatalf("want %s to be translated; got %v", method, unsupported)
+		}
+	}
+	src, err := g.source("main")
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	return src
+}
+
+func TestNativeCompile(t *testing.T) {
+	schemas := make(map[string]string)
+	for _, method := range []string{"count", "error", "is", "equal", "in", "validators"} {
+		schemas[method] = `{"type": "object", "properties": {"a": {"enum": [1]}},
+			"oneOf": [{"required": ["a"]}, {"required": ["b"]}]}`
+	}
+	out := runNative(t, map[string][]byte{
+		nativeFile: nativeSource(t, schemas),
+		"main.go": []byte(`package main
+
+import "fmt"
+
+func main() {
+	fmt.Print(nativeValidators["count"](map[string]interface{}{"a": 1.0}))
+}
+`),
+	})
+	if out != "<nil>" {
+		t.Errorf("want output=<nil>; got %s", out)
+	}
+}
+
+func TestNativeMultipleOf(t *testing.T) {
+	out := runNative(t, map[string][]byte{
+		nativeFile: nativeSource(t, map[string]string{
+			"price": `{"multipleOf": 0.1}`,
+			"count": `{"multipleOf": 3}`,
+		}),
+		"main.go": []byte(`package main
+
+import "fmt"
+
+func main() {
+	for _, v := range []float64{0.3, 1.2, 0.35} {
+		fmt.Println(nativeValidators["price"](v))
+	}
+	for _, v := range []float64{9, 10} {
+		fmt.Println(nativeValidators["count"](v))
+	}
+}
+`),
+	})
+	want := `<nil>
+<nil>
+(root): value must be a multiple of 0.1
+<nil>
+(root): value must be a multiple of 3
+`
+	if out != want {
+		t.Errorf("want output=%q; got %q", want, out)
+	}
+}
+
+func TestNativeStruct(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"name": {"type": "string", "minLength": 2},
+			"age": {"type": "integer", "maximum": 150}
+		}
+	}`
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(schema), &m); err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	g := newTypeGen([]string{"item"})
+	g.addSchema("item", m)
+	types, err := g.source("main")
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	out := runNative(t, map[string][]byte{
+		nativeFile: nativeSource(t, map[string]string{"item": schema}),
+		typesFile:  types,
+		"main.go": []byte(`package main
+
+import "fmt"
+
+func main() {
+	age := int64(200)
+	for _, v := range []interface{}{
+		Item{Name: "ab"},
+		Item{Name: "a"},
+		&Item{Name: "ab", Age: &age},
+		map[string]int{"name": 1},
+	} {
+		fmt.Println(nativeValidators["item"](v))
+	}
+}
+`),
+	})
+	want := `<nil>
+/name: string must be at least 2 characters long
+/age: value must be <= 150
+/name: value is not of type string
+`
+	if out != want {
+		t.Errorf("want output=%q; got %q", want, out)
+	}
+}
@@ -0,0 +1,402 @@
+package schemagen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// typesFile is a Go file to which generated types are stored.
+const typesFile = `types.go`
+
+// initialisms are words written in upper case in Go identifiers.
+var initialisms = map[string]bool{`api`: true, `html`: true, `http`: true,
+	`id`: true, `ip`: true, `json`: true, `uri`: true, `url`: true, `uuid`: true}
+
+// goName converts name of a schema, definition or property into exported Go
+// identifier, e.g. `user_id` becomes `UserID`.
+func goName(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var buf bytes.Buffer
+	for _, part := range parts {
+		if initialisms[strings.ToLower(part)] {
+			buf.WriteString(strings.ToUpper(part))
+			continue
+		}
+		r := []rune(part)
+		buf.WriteString(string(unicode.ToUpper(r[0])) + string(r[1:]))
+	}
+	id := buf.String()
+	if id == "" || unicode.IsDigit([]rune(id)[0]) {
+		id = "T" + id
+	}
+	return id
+}
+
+// bindNames are identifiers declared by `outputFile` file, which generated
+// types must not collide with. The file declares also Schema<Ident> constant
+// and Validate<Ident> function for each schema.
+var bindNames = []string{`Document`, `MustLoadAll`, `Schema`, `Schemas`,
+	`Validator`, `Validators`}
+
+// defType is a Go type generated for a definition.
+type defType struct {
+	// def is a JSON representation of the definition and of the ones it
+	// refers to.
+	def string
+	// name is a name of Go type.
+	name string
+}
+
+// typeGen generates Go types of schemas which belong to one package. Shared
+// definitions become named types, which are declared once per package.
+type typeGen struct {
+	// decls contains declarations of types in order of their generation.
+	decls []string
+	// used contains names of declared types.
+	used map[string]bool
+	// defs maps definition names to types generated for them, the same name
+	// may be given to different definitions by schemas' own definitions.
+	defs map[string][]defType
+	// root is a schema which types are being generated.
+	root map[string]interface{}
+	// refs maps definition names of root to names of their types.
+	refs map[string]string
+	// visiting contains references being followed, which ends walking
+	// cyclic ones.
+	visiting map[string]bool
+}
+
+// defKey returns JSON representation of definition name of defs followed by
+// representations of definitions it refers to, directly or not. Definitions
+// with equal keys are represented by the same Go type.
+func defKey(defs map[string]interface{}, name string) string {
+	seen := map[string]bool{name: true}
+	queue := []string{name}
+	for i := 0; i < len(queue); i++ {
+		walkRefs(defs[queue[i]], func(ref string) {
+			if dep, ok := definitionName(ref); ok && !seen[dep] {
+				if _, ok := defs[dep]; ok {
+					seen[dep] = true
+					queue = append(queue, dep)
+				}
+			}
+		})
+	}
+	sort.Strings(queue[1:])
+	var buf bytes.Buffer
+	for _, dep := range queue {
+		data, _ := json.Marshal(defs[dep])
+		fmt.Fprintf(&buf, "%q:%s\n", dep, data)
+	}
+	return buf.String()
+}
+
+// newTypeGen creates empty typeGen of package, which binds schemas named by
+// methods in `outputFile` file.
+func newTypeGen(methods []string) *typeGen {
+	g := &typeGen{used: make(map[string]bool), defs: make(map[string][]defType)}
+	for _, name := range bindNames {
+		g.used[name] = true
+	}
+	names := append([]string(nil), methods...)
+	sort.Strings(names)
+	for _, ident := range bindIdents(names) {
+		g.used[`Schema`+ident], g.used[`Validate`+ident] = true, true
+	}
+	return g
+}
+
+// name returns unique type name derived from base.
+func (g *typeGen) name(base string) string {
+	name := base
+	for i := 2; g.used[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	g.used[name] = true
+	return name
+}
+
+// addSchema generates types of schema read from file named method and types
+// of definitions injected into it.
+func (g *typeGen) addSchema(method string, schema map[string]interface{}) {
+	g.root, g.refs, g.visiting = schema, make(map[string]string), make(map[string]bool)
+	defs, _ := schema[definitionsKeyword(schema)].(map[string]interface{})
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// all the names are known before types are generated, as definitions
+	// may refer to each other.
+	var added []string
+	for _, name := range names {
+		data := defKey(defs, name)
+		for _, t := range g.defs[name] {
+			if t.def == data {
+				g.refs[name] = t.name
+				break
+			}
+		}
+		if _, ok := g.refs[name]; !ok {
+			g.refs[name] = g.name(goName(name))
+			g.defs[name] = append(g.defs[name], defType{def: data, name: g.refs[name]})
+			added = append(added, name)
+		}
+	}
+	for _, name := range added {
+		g.declare(g.refs[name], defs[name])
+	}
+	g.declare(g.name(goName(method)), schema)
+}
+
+// declare adds declaration of type name generated for schema v.
+func (g *typeGen) declare(name string, v interface{}) {
+	var decl string
+	if schema, ok := v.(map[string]interface{}); ok {
+		if desc, ok := schema[`description`].(string); ok {
+			decl = "// " + name + " " + strings.Join(strings.Fields(desc), " ") + "\n"
+		}
+	}
+	// the declaration is reserved before its type is generated, so types
+	// of nested objects follow it.
+	i := len(g.decls)
+	g.decls = append(g.decls, "")
+	g.decls[i] = decl + "type " + name + " " + g.goType(v, name, true)
+}
+
+// goType returns Go type for schema v. Objects with properties become structs,
+// which are declared as types named after name, unless they are at top level.
+func (g *typeGen) goType(v interface{}, name string, top bool) string {
+	schema, ok := v.(map[string]interface{})
+	if !ok {
+		return `interface{}`
+	}
+	if ref, ok := schema[`$ref`].(string); ok {
+		return g.refType(ref, name)
+	}
+	switch schemaType(schema) {
+	case `object`:
+		props, _ := g.properties(schema)
+		if len(props) == 0 {
+			if ap, ok := schema[`additionalProperties`].(map[string]interface{}); ok {
+				return `map[string]` + g.goType(ap, name+`Value`, false)
+			}
+			return `map[string]interface{}`
+		}
+		if !top {
+			nested := g.name(name)
+			g.declare(nested, schema)
+			return nested
+		}
+		return g.structType(schema, name)
+	case `array`:
+		if items, ok := schema[`items`].(map[string]interface{}); ok {
+			return `[]` + g.goType(items, name+`Item`, false)
+		}
+		return `[]interface{}`
+	case `string`:
+		return `string`
+	case `integer`:
+		return `int64`
+	case `number`:
+		return `float64`
+	case `boolean`:
+		return `bool`
+	}
+	return `interface{}`
+}
+
+// refType returns Go type for reference ref. References to definitions
+// are represented by their named types, other local references are resolved
+// against root schema.
+func (g *typeGen) refType(ref, name string) string {
+	file, frag := splitRef(ref)
+	toks, err := pointerTokens(frag)
+	if file != "" || err != nil || g.visiting[ref] {
+		return `interface{}`
+	}
+	if len(toks) == 2 && isDefinitionsKey(toks[0]) {
+		if t, ok := g.refs[toks[1]]; ok {
+			return t
+		}
+	}
+	target, err := resolvePointer(g.root, toks)
+	if err != nil {
+		return `interface{}`
+	}
+	g.visiting[ref] = true
+	defer delete(g.visiting, ref)
+	return g.goType(target, name, false)
+}
+
+// properties returns properties of object schema and names of the required
+// ones, including the ones of allOf members.
+func (g *typeGen) properties(schema map[string]interface{}) (map[string]interface{}, map[string]bool) {
+	props, req := make(map[string]interface{}), make(map[string]bool)
+	var collect func(v interface{})
+	collect = func(v interface{}) {
+		schema, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+		if ref, ok := schema[`$ref`].(string); ok {
+			if file, frag := splitRef(ref); file == "" && !g.visiting[ref] {
+				if toks, err := pointerTokens(frag); err == nil {
+					if target, err := resolvePointer(g.root, toks); err == nil {
+						g.visiting[ref] = true
+						collect(target)
+						delete(g.visiting, ref)
+					}
+				}
+			}
+			return
+		}
+		if m, ok := schema[`properties`].(map[string]interface{}); ok {
+			for name, prop := range m {
+				props[name] = prop
+			}
+		}
+		if names, ok := schema[`required`].([]interface{}); ok {
+			for _, name := range names {
+				if name, ok := name.(string); ok {
+					req[name] = true
+				}
+			}
+		}
+		if all, ok := schema[`allOf`].([]interface{}); ok {
+			for _, sub := range all {
+				collect(sub)
+			}
+		}
+	}
+	collect(schema)
+	return props, req
+}
+
+// structType returns Go struct for object schema, which type is named name.
+// Optional properties are represented by pointer fields, unless their types
+// are nillable already.
+func (g *typeGen) structType(schema map[string]interface{}, name string) string {
+	props, req := g.properties(schema)
+	names := make([]string, 0, len(props))
+	for prop := range props {
+		names = append(names, prop)
+	}
+	sort.Strings(names)
+	fields := make(map[string]bool, len(names))
+	var buf bytes.Buffer
+	buf.WriteString("struct {\n")
+	for _, prop := range names {
+		field := goName(prop)
+		for i := 2; fields[field]; i++ {
+			field = fmt.Sprintf("%s%d", goName(prop), i)
+		}
+		fields[field] = true
+		typ, tag := g.goType(props[prop], name+field, false), prop
+		if !req[prop] {
+			tag += `,omitempty`
+			if !strings.HasPrefix(typ, `[]`) && !strings.HasPrefix(typ, `map[`) && typ != `interface{}` {
+				typ = `*` + typ
+			}
+		}
+		fmt.Fprintf(&buf, "%s %s `json:%q`\n", field, typ, tag)
+	}
+	buf.WriteString("}")
+	return buf.String()
+}
+
+// schemaType returns JSON type of values described by schema. Nullable types
+// are represented by the type of non-null values. If the type is not
+// declared, it is deduced from keywords describing objects and arrays.
+func schemaType(schema map[string]interface{}) string {
+	switch typ := schema[`type`].(type) {
+	case string:
+		return typ
+	case []interface{}:
+		var types []string
+		for _, t := range typ {
+			if t, ok := t.(string); ok && t != `null` {
+				types = append(types, t)
+			}
+		}
+		if len(types) == 1 {
+			return types[0]
+		}
+		return ``
+	}
+	if _, ok := schema[`properties`]; ok {
+		return `object`
+	}
+	if all, ok := schema[`allOf`].([]interface{}); ok {
+		for _, sub := range all {
+			if sub, ok := sub.(map[string]interface{}); ok {
+				if schemaType(sub) == `object` {
+					return `object`
+				}
+				if _, ok := sub[`$ref`]; ok {
+					return `object`
+				}
+			}
+		}
+	}
+	if _, ok := schema[`items`]; ok {
+		return `array`
+	}
+	return ``
+}
+
+// source returns formatted Go source file of package pkg with generated types.
+func (g *typeGen) source(pkg string) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "package %s\n", pkg)
+	for _, decl := range g.decls {
+		buf.WriteString("\n" + decl + "\n")
+	}
+	return format.Source(buf.Bytes())
+}
+
+// createTypesFiles makes `typesFile` file for each service, which contains
+// Go types of service's schemas and of definitions they use.
+func (s *schg) createTypesFiles(schemaOutBase string) error {
+	gens := make(map[string]*typeGen)
+	methods := make(map[string][]string)
+	paths := make([]string, 0, len(s.merged))
+	for path := range s.merged {
+		paths = append(paths, path)
+		methods[s.service(path)] = append(methods[s.service(path)], filepath.Base(path))
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		serv := s.service(path)
+		if gens[serv] == nil {
+			gens[serv] = newTypeGen(methods[serv])
+		}
+		gens[serv].addSchema(filepath.Base(path), s.merged[path])
+	}
+	var errs Errors
+	for serv, g := range gens {
+		subdir := serv
+		if s.merge || serv == filepath.Base(schemaOutBase) {
+			subdir = ""
+		}
+		name := filepath.Join(schemaOutBase, subdir, typesFile)
+		src, err := g.source(serv)
+		if err != nil {
+			errs.add(newError(name, cannotWriteTypesErr, err))
+			continue
+		}
+		if err = ioutil.WriteFile(name, src, 0644); err != nil {
+			errs.add(fileError(name, nil, err))
+		}
+	}
+	return errs.err()
+}
@@ -0,0 +1,178 @@
+package schemagen
+
+import (
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoName(t *testing.T) {
+	cases := map[string]string{
+		"user":         "User",
+		"user_id":      "UserID",
+		"create-order": "CreateOrder",
+		"getURL":       "GetURL",
+		"2fa":          "T2fa",
+		"a.b c":        "ABC",
+		"":             "T",
+	}
+	for name, want := range cases {
+		if got := goName(name); got != want {
+			t.Errorf("want goName(%q)=%s; got %s", name, want, got)
+		}
+	}
+}
+
+func TestTypeGen(t *testing.T) {
+	schemas := []struct {
+		method string
+		schema string
+	}{
+		{"create_user", `{
+			"description": "Creates a user.",
+			"type": "object",
+			"required": ["name", "address"],
+			"properties": {
+				"name": {"type": "string"},
+				"age": {"type": "integer"},
+				"address": {"$ref": "#/definitions/address"},
+				"tags": {"type": "array", "items": {"type": "string"}},
+				"meta": {"type": "object", "additionalProperties": {"type": "number"}},
+				"options": {
+					"type": "object",
+					"properties": {"admin": {"type": ["boolean", "null"]}}
+				}
+			},
+			"definitions": {
+				"address": {
+					"type": "object",
+					"required": ["street"],
+					"properties": {
+						"street": {"type": "string"},
+						"zip": {"$ref": "#/definitions/zip"}
+					}
+				},
+				"zip": {"type": "string"}
+			}
+		}`},
+		{"get_user", `{
+			"allOf": [{"$ref": "#/definitions/address"}, {"properties": {"id": {"type": "integer"}}}],
+			"definitions": {
+				"address": {
+					"type": "object",
+					"required": ["street"],
+					"properties": {
+						"street": {"type": "string"},
+						"zip": {"$ref": "#/definitions/zip"}
+					}
+				},
+				"zip": {"type": "integer"}
+			}
+		}`},
+	}
+	want := `package user
+
+type Address struct {
+	Street string ` + "`json:\"street\"`" + `
+	Zip    *Zip   ` + "`json:\"zip,omitempty\"`" + `
+}
+
+type Zip string
+
+// CreateUser Creates a user.
+type CreateUser struct {
+	Address Address            ` + "`json:\"address\"`" + `
+	Age     *int64             ` + "`json:\"age,omitempty\"`" + `
+	Meta    map[string]float64 ` + "`json:\"meta,omitempty\"`" + `
+	Name    string             ` + "`json:\"name\"`" + `
+	Options *CreateUserOptions ` + "`json:\"options,omitempty\"`" + `
+	Tags    []string           ` + "`json:\"tags,omitempty\"`" + `
+}
+
+type CreateUserOptions struct {
+	Admin *bool ` + "`json:\"admin,omitempty\"`" + `
+}
+
+type Address2 struct {
+	Street string ` + "`json:\"street\"`" + `
+	Zip    *Zip2  ` + "`json:\"zip,omitempty\"`" + `
+}
+
+type Zip2 int64
+
+type GetUser struct {
+	ID     *int64 ` + "`json:\"id,omitempty\"`" + `
+	Street string ` + "`json:\"street\"`" + `
+	Zip    *Zip2  ` + "`json:\"zip,omitempty\"`" + `
+}
+`
+	g := newTypeGen(nil)
+	for _, s := range schemas {
+		var schema map[string]interface{}
+		if err := json.Unmarshal([]byte(s.schema), &schema); err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+		g.addSchema(s.method, schema)
+	}
+	src, err := g.source("user")
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	if string(src) != want {
+		t.Errorf("want source:\n%s\ngot:\n%s", want, src)
+	}
+}
+
+func TestGenerateTypes(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		"definitions.json": `{"definitions": {"id": {"type": "integer"}}}`,
+		"users/get.json": `{"type": "object", "required": ["id"],
+			"properties": {"id": {"$ref": "#/definitions/id"}}}`,
+		"orders/list.json": `{"type": "array", "items": {"$ref": "#/definitions/id"}}`,
+	})
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+	g := New(false)
+	g.Types = true
+	if err := g.Generate(dir, out); err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	for _, serv := range []string{"users", "orders"} {
+		src, err := ioutil.ReadFile(filepath.Join(out, serv, typesFile))
+		if err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+		if _, err = parser.ParseFile(token.NewFileSet(), typesFile, src, 0); err != nil {
+			t.Errorf("want err=nil; got %v", err)
+		}
+		if !strings.Contains(string(src), "package "+serv+"\n") ||
+			!strings.Contains(string(src), "type ID int64\n") {
+			t.Errorf("want package %s with ID type; got:\n%s", serv, src)
+		}
+	}
+}
+
+func TestTypeGenBindNames(t *testing.T) {
+	g := newTypeGen([]string{"validator", "schemas", "get"})
+	g.addSchema("validator", map[string]interface{}{"type": "string"})
+	g.addSchema("schemas", map[string]interface{}{"type": "string"})
+	g.addSchema("get", map[string]interface{}{"definitions": map[string]interface{}{
+		"schema_get":   map[string]interface{}{"type": "string"},
+		"validate_get": map[string]interface{}{"type": "string"},
+	}})
+	src, err := g.source("users")
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	for _, want := range []string{"type Validator2 string", "type Schemas2 string",
+		"type SchemaGet2 string", "type ValidateGet2 string"} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("want content (%s) to contain %q", src, want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package schemagen
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// layer is a set of definitions visible in a directory tree.
+type layer struct {
+	// defs contains definitions grouped by their names.
+	defs map[string]interface{}
+	// files maps definition names to files they were read from.
+	files map[string]string
+}
+
+// newLayer creates a layer with defs read from file.
+func newLayer(defs map[string]interface{}, file string) *layer {
+	l := &layer{defs: defs, files: make(map[string]string, len(defs))}
+	for name := range defs {
+		l.files[name] = file
+	}
+	return l
+}
+
+// layer returns definitions visible in dir, these are the definitions of
+// the closest directory up the tree which has its own definitions file.
+func (s *schg) layer(dir string) *layer {
+	for {
+		if l, ok := s.layers[dir]; ok {
+			return l
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return &layer{}
+		}
+		dir = parent
+	}
+}
+
+// inheritDefinitions reads definitions file located in dir and merges it with
+// definitions visible in parent directory. Definitions from dir override
+// inherited ones, every override which changes a definition is logged along
+// with inherited definitions which refer to the overridden one, as their
+// meaning changes in dir's subtree.
+func (s *schg) inheritDefinitions(dir string) error {
+	path := definitionsPath(dir)
+	defs, err := s.readDefinitions(path)
+	if err != nil {
+		return err
+	}
+	parent := s.layer(filepath.Dir(dir))
+	l := &layer{
+		defs:  make(map[string]interface{}, len(parent.defs)+len(defs)),
+		files: make(map[string]string, len(parent.defs)+len(defs)),
+	}
+	for name, def := range parent.defs {
+		l.defs[name], l.files[name] = def, parent.files[name]
+	}
+	overridden := make(map[string]bool)
+	for name, def := range defs {
+		if old, ok := parent.defs[name]; ok && !reflect.DeepEqual(old, def) {
+			log.Println(fmt.Sprintf(overrideDefinitionErr, path, name, parent.files[name]))
+			overridden[name] = true
+		}
+		l.defs[name], l.files[name] = def, path
+	}
+	if len(overridden) != 0 {
+		for name, def := range l.defs {
+			m, ok := def.(map[string]interface{})
+			if !ok || l.files[name] == path {
+				continue
+			}
+			var refs []string
+			for _, ref := range s.findReferences(m) {
+				if overridden[ref] {
+					refs = append(refs, ref)
+				}
+			}
+			if len(refs) != 0 {
+				sort.Strings(refs)
+				log.Println(fmt.Sprintf(overrideConflictErr, path, name,
+					l.files[name], strings.Join(refs, `, `)))
+			}
+		}
+	}
+	if err = checkReferences(path, l.defs, l.defs); err != nil {
+		return err
+	}
+	if err = s.checkCycles(l, path); err != nil {
+		return err
+	}
+	s.layers[dir] = l
+	return nil
+}
+
+// inheritRoots filters paths, so only the ones which input directories are
+// not nested in input directory of another path are left, whether they have
+// their own definitions file or not. In Inherit mode schemas of nested
+// directories are generated together with their parent's ones.
+func inheritRoots(paths []path) (roots []path) {
+	for _, p := range paths {
+		nested := false
+		for _, r := range paths {
+			if strings.HasPrefix(p.in, r.in+string(filepath.Separator)) {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			roots = append(roots, p)
+		}
+	}
+	return
+}
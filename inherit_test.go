@@ -0,0 +1,118 @@
+package schemagen
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInherit(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		definitionsFile: `{"definitions": {"id": {"type": "integer"},
+			"user": {"properties": {"id": {"$ref": "#/definitions/id"}}}}}`,
+		"sub/" + definitionsFile: `{"definitions": {"id": {"type": "string"},
+			"extra": {"$ref": "#/definitions/user"}}}`,
+		"sub/service/method.json":  `{"$ref": "#/definitions/extra"}`,
+		"sub/deeper/method.json":   `{"$ref": "#/definitions/id"}`,
+		"other/method.json":        `{"$ref": "#/definitions/user"}`,
+		"top/" + definitionsFile:   `{"definitions": {"id": {"type": "integer"}}}`,
+		"top/toplevel/method.json": `{"$ref": "#/definitions/user"}`,
+		"sub/service/missing.json": `{}`,
+	})
+	defer os.RemoveAll(dir)
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	schg := New(false)
+	schg.Inherit = true
+	dumped, err := walkTest(t, schg, dir)
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	tests := map[string]string{"service": "string", "deeper": "string", "other": "integer"}
+	for serv, typ := range tests {
+		def := dumped[serv]["method"].(map[string]interface{})["definitions"].(map[string]interface{})
+		id := def["id"].(map[string]interface{})
+		if id["type"] != typ {
+			t.Errorf("%s: want id of type %q; got %v", serv, typ, id)
+		}
+	}
+	// "top" redefines id identically, so it is not reported.
+	out := buf.String()
+	exp := []string{
+		filepath.Join(dir, "sub", definitionsFile) + ` overrides definition "id" of ` +
+			filepath.Join(dir, definitionsFile),
+		`meaning of definition "user" of ` + filepath.Join(dir, definitionsFile) +
+			`, which refers to overridden id`,
+	}
+	for _, e := range exp {
+		if !strings.Contains(out, e) {
+			t.Errorf("want log (%s) to contain %q", out, e)
+		}
+	}
+	if strings.Contains(out, filepath.Join(dir, "top")) {
+		t.Errorf("want log (%s) not to mention top directory", out)
+	}
+
+	// without Inherit mode subdirectories with definitions are ignored.
+	dumped, err = walkTest(t, New(false), dir)
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	if _, ok := dumped["service"]; ok {
+		t.Errorf("want \"service\" to be ignored")
+	}
+}
+
+func TestInheritMissing(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		definitionsFile:           `{"definitions": {"id": {"type": "integer"}}}`,
+		"sub/" + definitionsFile:  `{"definitions": {"extra": {"$ref": "#/definitions/nope"}}}`,
+		"sub/service/method.json": `{"$ref": "#/definitions/extra"}`,
+	})
+	defer os.RemoveAll(dir)
+	schg := New(false)
+	schg.Inherit = true
+	_, err := walkTest(t, schg, dir)
+	if err == nil || !strings.Contains(err.Error(), filepath.Join("sub", definitionsFile)) {
+		t.Errorf("want err naming sub/%s; got %v", definitionsFile, err)
+	}
+}
+
+func TestInheritRoots(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		"a/" + definitionsFile:     `{}`,
+		"a/b/" + definitionsFile:   `{}`,
+		"a/b/c/x.json":             `{}`,
+		"x/y.json":                 `{}`,
+		"ab/" + definitionsFile:    `{}`,
+		"d/e/" + definitionsFile:   `{}`,
+		"d/e/f/" + definitionsFile: `{}`,
+	})
+	defer os.RemoveAll(dir)
+	var paths []path
+	for _, p := range []string{"a", "a/b", "a/b/c", "ab", "d", "d/e", "d/e/f"} {
+		paths = append(paths, path{filepath.Join(dir, p), p})
+	}
+	var roots []string
+	for _, p := range inheritRoots(paths) {
+		roots = append(roots, p.out)
+	}
+	if exp := []string{"a", "ab", "d"}; !reflect.DeepEqual(roots, exp) {
+		t.Errorf("want roots=%v; got %v", exp, roots)
+	}
+	// paths without definitions file are generated on their own.
+	paths = []path{{filepath.Join(dir, "x"), "x"}, {filepath.Join(dir, "a"), "a"}}
+	roots = nil
+	for _, p := range inheritRoots(paths) {
+		roots = append(roots, p.out)
+	}
+	if exp := []string{"x", "a"}; !reflect.DeepEqual(roots, exp) {
+		t.Errorf("want roots=%v; got %v", exp, roots)
+	}
+}
@@ -0,0 +1,90 @@
+package schemagen
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Conflict is a policy of resolving conflicts between names of definitions
+// local to a schema file and shared definitions.
+type Conflict int
+
+const (
+	// ConflictError makes processing of schema fail.
+	ConflictError Conflict = iota
+	// LocalWins keeps local definition, so it shadows shared one.
+	LocalWins
+	// SharedWins replaces local definition with shared one.
+	SharedWins
+)
+
+var conflictNames = map[Conflict]string{
+	ConflictError: `error`,
+	LocalWins:     `local`,
+	SharedWins:    `shared`,
+}
+
+// String implements flag.Value interface.
+func (c *Conflict) String() string {
+	return conflictNames[*c]
+}
+
+// Set implements flag.Value interface, it accepts `error`, `local` and
+// `shared` values.
+func (c *Conflict) Set(v string) error {
+	for conflict, name := range conflictNames {
+		if name == v {
+			*c = conflict
+			return nil
+		}
+	}
+	return fmt.Errorf(unknownConflictErr, v)
+}
+
+// localDefinitions removes definitions sections from schema read from path
+// and returns definitions which should be kept according to s.Conflict
+// policy. Names of shadowed definitions are logged.
+func (s *schg) localDefinitions(path string, schema map[string]interface{}) (map[string]interface{}, error) {
+	local := make(map[string]interface{})
+	for _, key := range definitionsKeys {
+		cont, ok := schema[key]
+		if !ok {
+			continue
+		}
+		delete(schema, key)
+		m, ok := cont.(map[string]interface{})
+		if !ok {
+			return nil, newError(path, localDefinitionsErr, key)
+		}
+		for name, def := range m {
+			if _, ok := local[name]; ok {
+				return nil, newError(path, duplicatedDefinitionErr, name)
+			}
+			local[name] = def
+		}
+	}
+	var shadowed []string
+	for name := range local {
+		if _, ok := s.definitions[name]; ok {
+			shadowed = append(shadowed, name)
+		}
+	}
+	sort.Strings(shadowed)
+	switch {
+	case len(shadowed) == 0:
+	case s.Conflict == LocalWins:
+		for _, name := range shadowed {
+			log.Println(fmt.Sprintf(localShadowsErr, path, name))
+		}
+	case s.Conflict == SharedWins:
+		for _, name := range shadowed {
+			log.Println(fmt.Sprintf(sharedShadowsErr, path, name))
+			delete(local, name)
+		}
+	default:
+		return nil, newError(path, localConflictErr, strings.Join(shadowed, `, `))
+	}
+	return local, nil
+}
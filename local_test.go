@@ -0,0 +1,95 @@
+package schemagen
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLocalDefinitions(t *testing.T) {
+	files := map[string]string{
+		definitionsFile: `{"definitions": {"id": {"type": "integer"},
+			"name": {"type": "string"},
+			"user": {"properties": {"id": {"$ref": "#/definitions/id"}}}}}`,
+		"service/method.json": `{"properties": {
+			"user": {"$ref": "#/definitions/user"},
+			"helper": {"$ref": "#/$defs/helper"}},
+			"definitions": {"id": {"type": "string"}},
+			"$defs": {"helper": {"items": {"$ref": "#/definitions/name"}}}}`,
+	}
+	tests := map[Conflict]string{LocalWins: "string", SharedWins: "integer"}
+	for conflict, typ := range tests {
+		dir := newSchemaTree(t, files)
+		defer os.RemoveAll(dir)
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+		schg := New(false)
+		schg.Conflict = conflict
+		dumped, err := walkTest(t, schg, dir)
+		log.SetOutput(os.Stderr)
+		if err != nil {
+			t.Errorf("%s: want err=nil; got %v", conflict.String(), err)
+			continue
+		}
+		schema := dumped["service"]["method"].(map[string]interface{})
+		if _, ok := schema["$defs"]; ok {
+			t.Errorf("%s: want local \"$defs\" to be merged into definitions", conflict.String())
+		}
+		def := schema["definitions"].(map[string]interface{})
+		for _, name := range []string{"id", "name", "user", "helper"} {
+			if _, ok := def[name]; !ok {
+				t.Errorf("%s: want %q definition; got %v", conflict.String(), name, def)
+			}
+		}
+		if id := def["id"].(map[string]interface{}); id["type"] != typ {
+			t.Errorf("%s: want id of type %q; got %v", conflict.String(), typ, id)
+		}
+		if !strings.Contains(buf.String(), `"id"`) {
+			t.Errorf("%s: want log (%s) to report \"id\"", conflict.String(), buf.String())
+		}
+	}
+
+	// default policy fails on conflicts.
+	dir := newSchemaTree(t, files)
+	defer os.RemoveAll(dir)
+	if _, err := walkTest(t, New(false), dir); err == nil || !strings.Contains(err.Error(), "id") {
+		t.Errorf("want err naming id; got %v", err)
+	}
+}
+
+func TestLocalDefinitionsErrors(t *testing.T) {
+	tests := []string{
+		`{"definitions": []}`,
+		`{"definitions": {"a": {}}, "$defs": {"a": {}}}`,
+		`{"definitions": {"a": {"$ref": "#/definitions/missing"}}}`,
+	}
+	for _, schema := range tests {
+		dir := newSchemaTree(t, map[string]string{
+			definitionsFile:       `{"definitions": {}}`,
+			"service/method.json": schema,
+		})
+		defer os.RemoveAll(dir)
+		schg := New(false)
+		schg.Conflict = LocalWins
+		if _, err := walkTest(t, schg, dir); err == nil {
+			t.Errorf("%s: want err!=nil", schema)
+		}
+	}
+}
+
+func TestConflictSet(t *testing.T) {
+	var c Conflict
+	for _, name := range []string{"local", "shared", "error"} {
+		if err := c.Set(name); err != nil {
+			t.Errorf("want err=nil; got %v", err)
+		}
+		if c.String() != name {
+			t.Errorf("want c=%q; got %q", name, c.String())
+		}
+	}
+	if err := c.Set("other"); err == nil {
+		t.Errorf("want err!=nil")
+	}
+}
@@ -0,0 +1,377 @@
+package schemagen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// bundle resolves references to other JSON files found in schema read from
+// path. Referred fragments are copied into returned map, under names derived
+// from file path and pointer, and references are rewritten to point to
+// `#/definitions/<name>`, so the map can be injected into the schema.
+func (s *schg) bundle(path string, schema map[string]interface{}) (map[string]interface{}, error) {
+	bundled := make(map[string]interface{})
+	if err := s.bundleRefs(path, schema, bundled); err != nil {
+		return nil, err
+	}
+	return bundled, nil
+}
+
+// bundleRefs rewrites all references to external files found in v, which was
+// read from path. Local references are left untouched.
+func (s *schg) bundleRefs(path string, v interface{}, bundled map[string]interface{}) error {
+	return rewriteRefs(v, func(ref string) (string, error) {
+		file, frag := splitRef(ref)
+		if file == "" {
+			return ref, nil
+		}
+		target, err := resolveLocation(path, file)
+		if err != nil {
+			return "", newError(path, unresolvedRefErr, ref, err)
+		}
+		return s.bundleFragment(path, ref, target, frag, bundled)
+	})
+}
+
+// bundleFragment copies fragment of target file pointed by frag into bundled
+// map and returns local reference to it. Target is either a path of file or
+// URL of remote document. References found in the fragment are resolved
+// relative to target, so its local references are bundled as well, except
+// references of files from input directory, which point to shared definitions
+// since the files do not have such definitions themselves.
+func (s *schg) bundleFragment(src, ref, target, frag string,
+	bundled map[string]interface{}) (string, error) {
+	path, base, err := s.locate(src, ref, target)
+	if err != nil {
+		return "", err
+	}
+	toks, err := pointerTokens(frag)
+	if err != nil {
+		return "", newError(src, unresolvedRefErr, ref, err)
+	}
+	name := bundleName(base, toks)
+	local := `#/definitions/` + escapeFragment(escapeToken(name))
+	if _, ok := bundled[name]; ok {
+		return local, nil
+	}
+	doc, err := s.loadFile(path)
+	if ferr, ok := err.(*Error); ok {
+		// target file is malformed.
+		return "", ferr
+	}
+	if err != nil {
+		return "", newError(src, unresolvedRefErr, ref, err)
+	}
+	cont, err := resolvePointer(doc, toks)
+	if err != nil {
+		return "", newError(src, unresolvedRefErr, ref, err)
+	}
+	cont = copyJSON(cont)
+	// fragment is stored before its references are followed, that ends
+	// walking cyclic references.
+	bundled[name] = cont
+	return local, rewriteRefs(cont, func(r string) (string, error) {
+		file, fr := splitRef(r)
+		switch {
+		case file != "":
+			next, err := resolveLocation(target, file)
+			if err != nil {
+				return "", newError(target, unresolvedRefErr, r, err)
+			}
+			return s.bundleFragment(target, r, next, fr, bundled)
+		case isDefinitionsPointer(fr) && !isURL(target) &&
+			(isDefinitionsFile(filepath.Base(target)) || !hasDefinition(doc, fr)):
+			return r, nil
+		}
+		return s.bundleFragment(target, r, target, fr, bundled)
+	})
+}
+
+// locate returns path of the file which holds target location and a base
+// name for fragments bundled from it. Local files must be located in input
+// directory, remote documents are looked up in mirror directories.
+func (s *schg) locate(src, ref, target string) (path, base string, err error) {
+	if isURL(target) {
+		return s.mirror(src, ref, target)
+	}
+	rel, err := filepath.Rel(s.inBase, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", newError(src, refOutsideBaseErr, ref, s.inBase)
+	}
+	return target, rel, nil
+}
+
+// resolveLocation resolves file part of a reference against base location,
+// which is either a path of file or URL of remote document.
+func resolveLocation(base, file string) (string, error) {
+	if isURL(file) {
+		return file, nil
+	}
+	if !isURL(base) {
+		return filepath.Join(filepath.Dir(base), filepath.FromSlash(file)), nil
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	f, err := url.Parse(file)
+	if err != nil {
+		return "", err
+	}
+	return b.ResolveReference(f).String(), nil
+}
+
+// loadFile reads and unmarshals JSON or YAML file. Files are read once per
+// Generate's run. Malformed files are reported with *Error.
+func (s *schg) loadFile(path string) (interface{}, error) {
+	if doc, ok := s.files[path]; ok {
+		return doc, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := s.decode(path, data)
+	if err != nil {
+		return nil, err
+	}
+	if s.files == nil {
+		s.files = make(map[string]interface{})
+	}
+	s.files[path] = doc
+	return doc, nil
+}
+
+// rewriteRefs works like walkRefs, but replaces each `$ref` token with the
+// value returned by fn.
+func rewriteRefs(v interface{}, fn func(ref string) (string, error)) (err error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for name, cont := range v {
+			if ref, ok := cont.(string); ok && name == `$ref` {
+				if v[name], err = fn(ref); err != nil {
+					return
+				}
+				continue
+			}
+			if err = rewriteRefs(cont, fn); err != nil {
+				return
+			}
+		}
+	case []interface{}:
+		for _, cont := range v {
+			if err = rewriteRefs(cont, fn); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// splitRef splits reference into file and fragment parts.
+func splitRef(ref string) (file, frag string) {
+	if i := strings.Index(ref, `#`); i != -1 {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, ""
+}
+
+// pointerTokens splits JSON pointer, taken from URI fragment, into unescaped
+// reference tokens as described in RFC 6901.
+func pointerTokens(frag string) ([]string, error) {
+	ptr, err := url.PathUnescape(frag)
+	if err != nil {
+		return nil, fmt.Errorf(invalidPointerErr, frag)
+	}
+	if ptr == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(ptr, `/`) {
+		return nil, fmt.Errorf(invalidPointerErr, frag)
+	}
+	toks := strings.Split(ptr[1:], `/`)
+	for i, tok := range toks {
+		// every `~` must be a part of `~0` or `~1` escape sequence.
+		if strings.Count(tok, `~`) != strings.Count(tok, `~0`)+strings.Count(tok, `~1`) {
+			return nil, fmt.Errorf(invalidPointerErr, frag)
+		}
+		toks[i] = strings.Replace(strings.Replace(tok, `~1`, `/`, -1), `~0`, `~`, -1)
+	}
+	return toks, nil
+}
+
+// escapeToken escapes `~` and `/` characters of JSON pointer's reference token.
+func escapeToken(tok string) string {
+	return strings.Replace(strings.Replace(tok, `~`, `~0`, -1), `/`, `~1`, -1)
+}
+
+// fragmentEscaper escapes characters, which are not allowed in URI fragment
+// as they are, but may be found in names of definitions.
+var fragmentEscaper = strings.NewReplacer(`%`, `%25`, `#`, `%23`)
+
+// escapeFragment escapes JSON pointer, so it can be used as URI fragment.
+// Fragments are unescaped by pointerTokens.
+func escapeFragment(ptr string) string {
+	return fragmentEscaper.Replace(ptr)
+}
+
+// definitionName returns name of the definition which owns value pointed by
+// ref, e.g. `address` for #/definitions/address/properties/street. Second
+// return value is false if ref does not point into definitions.
+func definitionName(ref string) (string, bool) {
+	file, frag := splitRef(ref)
+	if file != "" || !strings.HasPrefix(ref, `#`) {
+		return "", false
+	}
+	toks, err := pointerTokens(frag)
+	if err != nil || len(toks) < 2 || !isDefinitionsKey(toks[0]) {
+		return "", false
+	}
+	return toks[1], true
+}
+
+// isDefinitionsKey reports whether tok is one of definitionsKeys.
+func isDefinitionsKey(tok string) bool {
+	for _, key := range definitionsKeys {
+		if tok == key {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDefinition reports whether doc has its own definition pointed into by
+// JSON pointer frag.
+func hasDefinition(doc interface{}, frag string) bool {
+	toks, err := pointerTokens(frag)
+	if err != nil || len(toks) < 2 {
+		return false
+	}
+	_, err = resolvePointer(doc, toks[:2])
+	return err == nil
+}
+
+// isDefinitionsPointer reports whether JSON pointer frag points into
+// definitions section.
+func isDefinitionsPointer(frag string) bool {
+	toks, err := pointerTokens(frag)
+	return err == nil && len(toks) > 1 && isDefinitionsKey(toks[0])
+}
+
+// definitionsRef rewrites local reference into definitions section, so it
+// uses key keyword. Other references are returned unchanged.
+func definitionsRef(ref, key string) string {
+	file, frag := splitRef(ref)
+	if file != "" || !strings.HasPrefix(ref, `#`) {
+		return ref
+	}
+	toks, err := pointerTokens(frag)
+	if err != nil || len(toks) == 0 || !isDefinitionsKey(toks[0]) || toks[0] == key {
+		return ref
+	}
+	toks[0] = key
+	for i := range toks {
+		toks[i] = escapeToken(toks[i])
+	}
+	return `#/` + escapeFragment(strings.Join(toks, `/`))
+}
+
+// definitionsKeyword returns keyword under which definitions are injected
+// into schema, it is `$defs` for schemas which declare draft 2019-09 or
+// newer with `$schema` keyword and `definitions` for older ones.
+func definitionsKeyword(schema map[string]interface{}) string {
+	if draft, ok := schema[`$schema`].(string); ok &&
+		(strings.Contains(draft, `/draft/2019-09/`) || strings.Contains(draft, `/draft/2020-12/`)) {
+		return `$defs`
+	}
+	return `definitions`
+}
+
+// checkReferences ensures that every reference to definitions found in v,
+// which was read from path, can be resolved against def map.
+func checkReferences(path string, v interface{}, def map[string]interface{}) (err error) {
+	walkRefs(v, func(ref string) {
+		file, frag := splitRef(ref)
+		if err != nil || file != "" || !strings.HasPrefix(ref, `#`) {
+			return
+		}
+		toks, e := pointerTokens(frag)
+		switch {
+		case e != nil:
+			err = newError(path, unresolvedRefErr, ref, e)
+		case len(toks) != 0 && isDefinitionsKey(toks[0]):
+			if _, e = resolvePointer(def, toks[1:]); e != nil {
+				err = newError(path, unresolvedRefErr, ref, e)
+			}
+		}
+	})
+	return
+}
+
+// isURL reports whether reference's file part is an absolute URL.
+func isURL(file string) bool {
+	return strings.Contains(file, `://`)
+}
+
+// bundleName creates definition name for a fragment of a file, which path
+// is relative to input directory, e.g. common/address.json#/properties/zip
+// is bundled under the same name. Names of distinct fragments differ, as
+// `#` and `%` characters of the path are escaped like in URI and fragment
+// is a JSON pointer.
+func bundleName(rel string, toks []string) string {
+	name := fragmentEscaper.Replace(filepath.ToSlash(rel))
+	if len(toks) != 0 {
+		ptr := make([]string, len(toks))
+		for i, tok := range toks {
+			ptr[i] = escapeToken(tok)
+		}
+		name += `#/` + strings.Join(ptr, `/`)
+	}
+	return name
+}
+
+// resolvePointer returns the part of JSON document doc pointed by toks.
+func resolvePointer(doc interface{}, toks []string) (interface{}, error) {
+	for _, tok := range toks {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			cont, ok := v[tok]
+			if !ok {
+				return nil, fmt.Errorf(missingPointerErr, tok)
+			}
+			doc = cont
+		case []interface{}:
+			i, err := strconv.Atoi(tok)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf(missingPointerErr, tok)
+			}
+			doc = v[i]
+		default:
+			return nil, fmt.Errorf(missingPointerErr, tok)
+		}
+	}
+	return doc, nil
+}
+
+// copyJSON makes a deep copy of unmarshaled JSON value.
+func copyJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for name, cont := range v {
+			m[name] = copyJSON(cont)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, cont := range v {
+			a[i] = copyJSON(cont)
+		}
+		return a
+	}
+	return v
+}
@@ -0,0 +1,469 @@
+package schemagen
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newSchemaTree creates temporary directory containing given files.
+func newSchemaTree(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir(os.TempDir(), "schematree")
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	for name, cont := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+		if err = ioutil.WriteFile(path, []byte(cont), 0644); err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+	}
+	return dir
+}
+
+// walkTest walks dir and returns unmarshaled schemas it dumped,
+// grouped by service and method names.
+func walkTest(t *testing.T, schg *schg, dir string) (map[string]map[string]interface{}, error) {
+	schg.inBase, schg.defFile = dir, definitionsPath(dir)
+	if err := schg.loadDefinitions(dir); err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	if err := schg.walk(dir); err != nil {
+		return nil, err
+	}
+	dumped := make(map[string]map[string]interface{})
+	for serv := range schg.sources {
+		schemas, err := schg.serviceSchemas(serv)
+		if err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+		dumped[serv] = make(map[string]interface{})
+		for name, data := range schemas {
+			var schema map[string]interface{}
+			if err = json.Unmarshal(data, &schema); err != nil {
+				t.Fatalf("want err=nil; got %v", err)
+			}
+			dumped[serv][name] = schema
+		}
+	}
+	return dumped, nil
+}
+
+func TestBundle(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		"common/address.json": `{"properties": {
+			"zip": {"type": "string"},
+			"street": {"$ref": "#/properties/zip"},
+			"id": {"$ref": "#/definitions/id"},
+			"self": {"$ref": "address.json#/properties/street"}}}`,
+		"service/method.json": `{"properties": {
+			"street": {"$ref": "../common/address.json#/properties/street"},
+			"address": {"$ref": "../common/address.json"},
+			"local": {"$ref": "#/properties/street"}}}`,
+	})
+	defer os.RemoveAll(dir)
+	schg := New(false)
+	schg.inBase = dir
+	path := filepath.Join(dir, "service", "method.json")
+	var schema map[string]interface{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	if err = json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+
+	bundled, err := schg.bundle(path, schema)
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	exp := map[string]interface{}{
+		"common/address.json#/properties/street": map[string]interface{}{
+			"$ref": "#/definitions/common~1address.json%23~1properties~1zip"},
+		"common/address.json#/properties/zip": map[string]interface{}{"type": "string"},
+	}
+	for name, cont := range exp {
+		if !reflect.DeepEqual(bundled[name], cont) {
+			t.Errorf("want bundled[%q]=%v; got %v", name, cont, bundled[name])
+		}
+	}
+	whole, ok := bundled["common/address.json"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("want \"common/address.json\" to be bundled; got %v", bundled)
+	}
+	props := whole["properties"].(map[string]interface{})
+	if ref := props["id"].(map[string]interface{})["$ref"]; ref != "#/definitions/id" {
+		t.Errorf("want shared reference to be kept; got %v", ref)
+	}
+	if ref := props["self"].(map[string]interface{})["$ref"]; ref != "#/definitions/common~1address.json%23~1properties~1street" {
+		t.Errorf("want self reference to be bundled; got %v", ref)
+	}
+	props = schema["properties"].(map[string]interface{})
+	exprefs := map[string]string{
+		"street":  "#/definitions/common~1address.json%23~1properties~1street",
+		"address": "#/definitions/common~1address.json",
+		"local":   "#/properties/street",
+	}
+	for prop, ref := range exprefs {
+		if got := props[prop].(map[string]interface{})["$ref"]; got != ref {
+			t.Errorf("want %s reference=%q; got %q", prop, ref, got)
+		}
+	}
+	var found bool
+	for _, ref := range schg.findReferences(bundled) {
+		found = found || ref == "id"
+	}
+	if !found {
+		t.Errorf("want bundled fragments to refer to \"id\"")
+	}
+}
+
+func TestBundleErrors(t *testing.T) {
+	tests := map[string]string{
+		"missing.json":             "service/method.json",
+		"../common/missing.json":   "service/method.json",
+		"../common/a.json#/nope":   "service/method.json",
+		"../common/a.json#nope":    "service/method.json",
+		"../common/b.json#/x":      "common/b.json",
+		"../../outside.json#/type": "service/method.json",
+	}
+	for ref, file := range tests {
+		files := map[string]string{
+			definitionsFile:       `{"definitions": {}}`,
+			"common/a.json":       `{"type": "string"}`,
+			"service/method.json": `{"items": [{"$ref": "` + ref + `"}]}`,
+		}
+		// common/b.json is a broken schema on its own.
+		if file == "common/b.json" {
+			files[file] = `{"x": {"$ref": "missing.json"}}`
+		}
+		dir := newSchemaTree(t, files)
+		defer os.RemoveAll(dir)
+		out, err := ioutil.TempDir(os.TempDir(), "out")
+		if err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+		defer os.RemoveAll(out)
+		err = New(false).Generate(dir, out)
+		if err == nil {
+			t.Errorf("%s: want err!=nil", ref)
+			continue
+		}
+		if !strings.Contains(err.Error(), filepath.FromSlash(file)) {
+			t.Errorf("%s: want err (%v) to contain %q", ref, err, file)
+		}
+	}
+}
+
+func TestGenerateBundle(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		definitionsFile: `{"definitions": {"id": {"type": "integer"},
+			"zip": {"$ref": "common/address.json#/properties/zip"}}}`,
+		"common/address.json": `{"properties": {"zip": {"type": "string"}}}`,
+		"service/method.json": `{"properties": {
+			"zip": {"$ref": "#/definitions/zip"},
+			"id": {"$ref": "../common/id.json"}}}`,
+		"common/id.json": `{"$ref": "#/definitions/id"}`,
+	})
+	defer os.RemoveAll(dir)
+	out, err := ioutil.TempDir(os.TempDir(), "out")
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	defer os.RemoveAll(out)
+	if err = New(false).Generate(dir, out); err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	for _, serv := range []string{"service", "common"} {
+		if _, err = os.Stat(filepath.Join(out, serv, "schema.go")); err != nil {
+			t.Errorf("want err=nil; got %v", err)
+		}
+	}
+}
+
+func TestPointerTokens(t *testing.T) {
+	tests := map[string][]string{
+		"":                     nil,
+		"/":                    {""},
+		"/definitions/a":       {"definitions", "a"},
+		"/definitions/a~1b/c":  {"definitions", "a/b", "c"},
+		"/definitions/m~0n":    {"definitions", "m~n"},
+		"/definitions/~01":     {"definitions", "~1"},
+		"/definitions/c%25d/0": {"definitions", "c%d", "0"},
+		"/definitions/e%20f":   {"definitions", "e f"},
+	}
+	for ptr, exp := range tests {
+		toks, err := pointerTokens(ptr)
+		if err != nil {
+			t.Errorf("%q: want err=nil; got %v", ptr, err)
+			continue
+		}
+		if !reflect.DeepEqual(toks, exp) {
+			t.Errorf("%q: want toks=%q; got %q", ptr, exp, toks)
+		}
+	}
+	for _, ptr := range []string{"definitions/a", "/definitions/a~", "/a~2b", "/%zz"} {
+		if _, err := pointerTokens(ptr); err == nil {
+			t.Errorf("%q: want err!=nil", ptr)
+		}
+	}
+}
+
+func TestFindReferencesPointers(t *testing.T) {
+	schema := `{"properties": {
+		"street": {"$ref": "#/definitions/address/properties/street"},
+		"slash": {"$ref": "#/definitions/a~1b"},
+		"tilde": {"$ref": "#/definitions/m~0n/items/0"},
+		"other": {"$ref": "#/properties/street"},
+		"broken": {"$ref": "#/definitions/x~"}}}`
+	refs := findReferencesTest(t, schema, New(false))
+	exp := map[string]bool{"address": true, "a/b": true, "m~n": true}
+	if len(refs) != len(exp) {
+		t.Fatalf("want len(refs)=%d; got %v", len(exp), refs)
+	}
+	for _, ref := range refs {
+		if !exp[ref] {
+			t.Errorf("want %q not to be in refs", ref)
+		}
+	}
+}
+
+func TestCheckReferences(t *testing.T) {
+	var def map[string]interface{}
+	err := json.Unmarshal([]byte(`{"address": {"properties": {"street": {}}},
+		"a/b": {"items": [{"type": "string"}]}}`), &def)
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	valid := []string{
+		"#/definitions/address",
+		"#/definitions/address/properties/street",
+		"#/definitions/a~1b/items/0",
+		"#/properties/x",
+		"#",
+		"other.json#/definitions/nope",
+	}
+	for _, ref := range valid {
+		if err = checkReferences("f.json", map[string]interface{}{"$ref": ref}, def); err != nil {
+			t.Errorf("%q: want err=nil; got %v", ref, err)
+		}
+	}
+	invalid := []string{
+		"#/definitions/nope",
+		"#/definitions/address/properties/city",
+		"#/definitions/a~1b/items/1",
+		"#/definitions/a/b",
+		"#/definitions/x~",
+	}
+	for _, ref := range invalid {
+		err = checkReferences("f.json", map[string]interface{}{"$ref": ref}, def)
+		if err == nil || !strings.Contains(err.Error(), "f.json") {
+			t.Errorf("%q: want err!=nil naming f.json; got %v", ref, err)
+		}
+	}
+}
+
+func TestGenerateDeepPointer(t *testing.T) {
+	defs := `{"definitions": {"address": {"properties": {"street": {"type": "string"}}}}}`
+	tests := map[string]bool{
+		`{"$ref": "#/definitions/address/properties/street"}`: true,
+		`{"$ref": "#/definitions/address/properties/city"}`:   false,
+	}
+	for schema, ok := range tests {
+		dir := newSchemaTree(t, map[string]string{
+			definitionsFile:       defs,
+			"service/method.json": schema,
+		})
+		defer os.RemoveAll(dir)
+		out, err := ioutil.TempDir(os.TempDir(), "out")
+		if err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+		defer os.RemoveAll(out)
+		err = New(false).Generate(dir, out)
+		if ok && err != nil {
+			t.Errorf("%s: want err=nil; got %v", schema, err)
+		}
+		if !ok && err == nil {
+			t.Errorf("%s: want err!=nil", schema)
+		}
+	}
+}
+
+func TestLoadDefinitionsDefs(t *testing.T) {
+	tests := map[string][]string{
+		`{"$defs": {"id": {"type": "integer"}}}`:                                 {"id"},
+		`{"definitions": {"id": {}}, "$defs": {"name": {"type": "string"}}}`:     {"id", "name"},
+		`{"definitions": {"id": {}}, "$defs": {"name": {"$ref": "#/$defs/id"}}}`: {"id", "name"},
+		`{"definitions": {"id": {}}, "$defs": {"id": {}}}`:                       nil,
+		`{"definitions": {"id": {}}, "$defs": 1}`:                                nil,
+		`{"$defs": {"name": {"$ref": "#/definitions/missing"}}}`:                 nil,
+	}
+	for defs, names := range tests {
+		dir := newSchemaTree(t, map[string]string{definitionsFile: defs})
+		defer os.RemoveAll(dir)
+		schg := New(false)
+		err := schg.loadDefinitions(dir)
+		if names == nil {
+			if err == nil {
+				t.Errorf("%s: want err!=nil", defs)
+			}
+			if schg.definitions != nil {
+				t.Errorf("%s: want schg.definitions=nil; got %v", defs, schg.definitions)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: want err=nil; got %v", defs, err)
+			continue
+		}
+		if len(schg.definitions) != len(names) {
+			t.Errorf("%s: want %d definitions; got %v", defs, len(names), schg.definitions)
+		}
+		for _, name := range names {
+			if _, ok := schg.definitions[name]; !ok {
+				t.Errorf("%s: want %q definition", defs, name)
+			}
+		}
+	}
+}
+
+func TestWalkDefs(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		definitionsFile: `{"$defs": {"id": {"type": "integer"},
+			"user": {"properties": {"id": {"$ref": "#/$defs/id"}}}},
+			"definitions": {"name": {"type": "string"}}}`,
+		"old/method.json": `{"$schema": "http://json-schema.org/draft-07/schema#",
+			"properties": {"user": {"$ref": "#/$defs/user"}}}`,
+		"new/method.json": `{"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"properties": {"user": {"$ref": "#/definitions/user"},
+			"name": {"$ref": "#/definitions/name"}}}`,
+	})
+	defer os.RemoveAll(dir)
+	dumped, err := walkTest(t, New(false), dir)
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	tests := map[string]string{"old": "definitions", "new": "$defs"}
+	for serv, key := range tests {
+		schema := dumped[serv]["method"].(map[string]interface{})
+		def, ok := schema[key].(map[string]interface{})
+		if !ok {
+			t.Errorf("%s: want %q section; got %v", serv, key, schema)
+			continue
+		}
+		user := def["user"].(map[string]interface{})["properties"].(map[string]interface{})
+		if ref := user["id"].(map[string]interface{})["$ref"]; ref != "#/"+key+"/id" {
+			t.Errorf("%s: want ref=%q; got %v", serv, "#/"+key+"/id", ref)
+		}
+		walkRefs(schema, func(ref string) {
+			if !strings.HasPrefix(ref, "#/"+key+"/") {
+				t.Errorf("%s: want ref %q to point into %q", serv, ref, key)
+			}
+		})
+	}
+}
+
+func TestBundleNames(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		"a/b.json":   `{"c": {"type": "string"}}`,
+		"a/b/c.json": `{"type": "integer"}`,
+		"a.b.json":   `{"type": "boolean"}`,
+		"service/method.json": `{"properties": {
+			"fragment": {"$ref": "../a/b.json#/c"},
+			"file": {"$ref": "../a/b/c.json"},
+			"dotted": {"$ref": "../a.b.json"},
+			"whole": {"$ref": "../a/b.json"}}}`,
+	})
+	defer os.RemoveAll(dir)
+	schg := New(false)
+	schg.inBase = dir
+	path := filepath.Join(dir, "service", "method.json")
+	var schema map[string]interface{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	if err = json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	bundled, err := schg.bundle(path, schema)
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	types := map[string]interface{}{
+		"fragment": "string",
+		"file":     "integer",
+		"dotted":   "boolean",
+	}
+	props := schema["properties"].(map[string]interface{})
+	for prop, typ := range types {
+		ref := props[prop].(map[string]interface{})["$ref"].(string)
+		_, frag := splitRef(ref)
+		toks, err := pointerTokens(frag)
+		if err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+		cont, err := resolvePointer(map[string]interface{}{"definitions": bundled}, toks)
+		if err != nil {
+			t.Fatalf("want err=nil (%s); got %v", prop, err)
+		}
+		if got := cont.(map[string]interface{})["type"]; got != typ {
+			t.Errorf("want %s to refer to %s; got %v (%s)", prop, typ, got, ref)
+		}
+	}
+	if len(bundled) != 4 {
+		t.Errorf("want 4 bundled fragments; got %v", bundled)
+	}
+}
+
+func TestBundleOwnDefinitions(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		definitionsFile: `{"definitions": {
+			"zip": {"type": "integer"},
+			"id": {"type": "integer"}}}`,
+		"common/address.json": `{"properties": {
+			"zip": {"$ref": "#/definitions/zip"},
+			"id": {"$ref": "#/definitions/id"}},
+			"definitions": {"zip": {"type": "string", "pattern": "^[0-9]{5}$"}}}`,
+		"service/method.json": `{"properties": {
+			"address": {"$ref": "../common/address.json"}}}`,
+	})
+	defer os.RemoveAll(dir)
+	schg := New(false)
+	schg.inBase = dir
+	path := filepath.Join(dir, "service", "method.json")
+	var schema map[string]interface{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	if err = json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	bundled, err := schg.bundle(path, schema)
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	exp := map[string]interface{}{"type": "string", "pattern": "^[0-9]{5}$"}
+	if def := bundled["common/address.json#/definitions/zip"]; !reflect.DeepEqual(def, exp) {
+		t.Errorf("want own definition %v to be bundled; got %v", exp, def)
+	}
+	props := bundled["common/address.json"].(map[string]interface{})["properties"].(map[string]interface{})
+	for prop, want := range map[string]string{
+		"zip": "#/definitions/common~1address.json%23~1definitions~1zip",
+		"id":  "#/definitions/id",
+	} {
+		if ref := props[prop].(map[string]interface{})["$ref"]; ref != want {
+			t.Errorf("want %s to refer to %s; got %v", prop, want, ref)
+		}
+	}
+}
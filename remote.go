@@ -0,0 +1,29 @@
+package schemagen
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// mirror returns path of the file which mirrors remote document located at
+// url and a base name for fragments bundled from it, which is the url itself,
+// so it never equals a relative path of a local file. The file is looked up
+// in a directory of s.Mirrors assigned to the longest prefix of url.
+func (s *schg) mirror(src, ref, url string) (path, base string, err error) {
+	var prefix string
+	for p := range s.Mirrors {
+		if strings.HasPrefix(url, p) && len(p) > len(prefix) {
+			prefix = p
+		}
+	}
+	if prefix == "" {
+		return "", "", newError(src, noMirrorErr, ref)
+	}
+	dir := s.Mirrors[prefix]
+	path = filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(url, prefix)))
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", newError(src, refOutsideBaseErr, ref, dir)
+	}
+	return path, url, nil
+}
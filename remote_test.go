@@ -0,0 +1,129 @@
+package schemagen
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMirror(t *testing.T) {
+	mirror := newSchemaTree(t, map[string]string{
+		"Point.json": `{"properties": {
+			"coordinates": {"$ref": "#/definitions/coords"},
+			"bbox": {"$ref": "BBox.json"}},
+			"definitions": {"coords": {"type": "array"}}}`,
+		"BBox.json":    `{"type": "array", "minItems": 4}`,
+		"other/x.json": `{"type": "string"}`,
+	})
+	defer os.RemoveAll(mirror)
+	other := newSchemaTree(t, map[string]string{"x.json": `{"type": "null"}`})
+	defer os.RemoveAll(other)
+	dir := newSchemaTree(t, map[string]string{
+		definitionsFile: `{"definitions": {"id": {"type": "integer"}}}`,
+		"service/method.json": `{"properties": {
+			"point": {"$ref": "https://geojson.org/schema/Point.json"},
+			"x": {"$ref": "https://geojson.org/schema/other/x.json"},
+			"id": {"$ref": "#/definitions/id"}}}`,
+	})
+	defer os.RemoveAll(dir)
+
+	schg := New(false)
+	schg.Mirrors = map[string]string{
+		"https://geojson.org/schema/":       mirror,
+		"https://geojson.org/schema/other/": other,
+	}
+	dumped, err := walkTest(t, schg, dir)
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	schema := dumped["service"]["method"].(map[string]interface{})
+	def := schema["definitions"].(map[string]interface{})
+	for _, name := range []string{"id", "https://geojson.org/schema/Point.json",
+		"https://geojson.org/schema/Point.json#/definitions/coords",
+		"https://geojson.org/schema/BBox.json", "https://geojson.org/schema/other/x.json"} {
+		if _, ok := def[name]; !ok {
+			t.Errorf("want %q definition; got %v", name, def)
+		}
+	}
+	if x := def["https://geojson.org/schema/other/x.json"].(map[string]interface{}); x["type"] != "null" {
+		t.Errorf("want the longest prefix to be used; got %v", x)
+	}
+	walkRefs(schema, func(ref string) {
+		if isURL(ref) {
+			t.Errorf("want %q to be bundled", ref)
+		}
+	})
+}
+
+func TestMirrorErrors(t *testing.T) {
+	// a.json refers to b.json, which is not mirrored.
+	mirror := newSchemaTree(t, map[string]string{
+		"a.json": `{"$ref": "https://example.com/b.json"}`,
+	})
+	defer os.RemoveAll(mirror)
+	tests := map[string]string{
+		"https://example.com/a.json":       "https://example.com/a.json",
+		"https://example.com/missing.json": "service",
+		"https://unknown.org/a.json":       "service",
+	}
+	for ref, src := range tests {
+		dir := newSchemaTree(t, map[string]string{
+			definitionsFile:       `{"definitions": {}}`,
+			"service/method.json": `{"$ref": "` + ref + `"}`,
+		})
+		defer os.RemoveAll(dir)
+		schg := New(false)
+		schg.Mirrors = map[string]string{"https://example.com/": mirror}
+		_, err := walkTest(t, schg, dir)
+		if err == nil || !strings.Contains(err.Error(), src) {
+			t.Errorf("%s: want err naming %s; got %v", ref, src, err)
+		}
+	}
+}
+
+func TestMirrorLocalClash(t *testing.T) {
+	mirror := newSchemaTree(t, map[string]string{"Point.json": `{"type": "array"}`})
+	defer os.RemoveAll(mirror)
+	dir := newSchemaTree(t, map[string]string{
+		"geojson.org/schema/Point.json": `{"type": "string"}`,
+		"service/method.json": `{"properties": {
+			"remote": {"$ref": "https://geojson.org/schema/Point.json"},
+			"local": {"$ref": "../geojson.org/schema/Point.json"}}}`,
+	})
+	defer os.RemoveAll(dir)
+	schg := New(false)
+	schg.inBase = dir
+	schg.Mirrors = map[string]string{"https://geojson.org/schema/": mirror}
+	path := filepath.Join(dir, "service", "method.json")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	var schema map[string]interface{}
+	if err = json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	bundled, err := schg.bundle(path, schema)
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	props := schema["properties"].(map[string]interface{})
+	for prop, typ := range map[string]string{"remote": "array", "local": "string"} {
+		ref := props[prop].(map[string]interface{})["$ref"].(string)
+		_, frag := splitRef(ref)
+		toks, err := pointerTokens(frag)
+		if err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+		cont, err := resolvePointer(map[string]interface{}{"definitions": bundled}, toks)
+		if err != nil {
+			t.Fatalf("want err=nil (%s); got %v", prop, err)
+		}
+		if got := cont.(map[string]interface{})["type"]; got != typ {
+			t.Errorf("want %s to refer to %s; got %v (%s)", prop, typ, got, ref)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package schemagen
+
+import "fmt"
+
+// Runtime is a library, which validates documents in generated bind.go files.
+// Whichever is chosen, the files expose schemas with the same Validator
+// interface.
+type Runtime int
+
+const (
+	// RuntimeSigu399 uses github.com/sigu-399/gojsonschema package.
+	RuntimeSigu399 Runtime = iota
+	// RuntimeXeipuuv uses github.com/xeipuuv/gojsonschema package.
+	RuntimeXeipuuv
+	// RuntimeSanthoshTekuri uses github.com/santhosh-tekuri/jsonschema/v5
+	// package.
+	RuntimeSanthoshTekuri
+)
+
+var runtimeNames = map[Runtime]string{
+	RuntimeSigu399:        `sigu-399`,
+	RuntimeXeipuuv:        `xeipuuv`,
+	RuntimeSanthoshTekuri: `santhosh-tekuri`,
+}
+
+// String implements flag.Value interface.
+func (r *Runtime) String() string {
+	return runtimeNames[*r]
+}
+
+// Set implements flag.Value interface, it accepts `sigu-399`, `xeipuuv` and
+// `santhosh-tekuri` values.
+func (r *Runtime) Set(v string) error {
+	for rt, name := range runtimeNames {
+		if name == v {
+			*r = rt
+			return nil
+		}
+	}
+	return fmt.Errorf(unknownRuntimeErr, v)
+}
+
+// runtimeAdapter describes code of bind.go file, which is specific to
+// a runtime.
+type runtimeAdapter struct {
+	// std are import paths of standard packages used by the code.
+	std []string
+	// lib is an import path of the runtime.
+	lib string
+	// doc is a Go type of compiled schemas.
+	doc string
+	// adapter declares schemagenCompile function and schemagenValidator
+	// type, which implements Validator interface. Unexported identifiers
+	// of the file are prefixed, so they do not collide with user's code.
+	adapter string
+}
+
+var runtimeAdapters = map[Runtime]runtimeAdapter{
+	RuntimeSigu399: {
+		std: []string{`encoding/json`, `errors`, `fmt`, `strings`},
+		lib: `github.com/sigu-399/gojsonschema`,
+		doc: `*gojsonschema.JsonSchemaDocument`,
+		adapter: `
+// schemagenCompile compiles schema named name, which is unmarshaled from raw.
+func schemagenCompile(name string, raw []byte, schema interface{}) (*gojsonschema.JsonSchemaDocument, error) {
+	return gojsonschema.NewJsonSchemaDocument(schema)
+}
+
+// schemagenValidator adapts compiled schema to Validator interface.
+type schemagenValidator struct{ s *gojsonschema.JsonSchemaDocument }
+
+// Validate implements Validator interface.
+func (v schemagenValidator) Validate(doc interface{}) error {
+	res := v.s.Validate(doc)
+	if res.IsValid() {
+		return nil
+	}
+	return errors.New(strings.Join(res.GetErrorMessages(), "; "))
+}
+`,
+	},
+	RuntimeXeipuuv: {
+		std: []string{`encoding/json`, `errors`, `fmt`, `strings`},
+		lib: `github.com/xeipuuv/gojsonschema`,
+		doc: `*gojsonschema.Schema`,
+		adapter: `
+// schemagenCompile compiles schema named name, which is unmarshaled from raw.
+func schemagenCompile(name string, raw []byte, schema interface{}) (*gojsonschema.Schema, error) {
+	return gojsonschema.NewSchema(gojsonschema.NewGoLoader(schema))
+}
+
+// schemagenValidator adapts compiled schema to Validator interface.
+type schemagenValidator struct{ s *gojsonschema.Schema }
+
+// Validate implements Validator interface.
+func (v schemagenValidator) Validate(doc interface{}) error {
+	res, err := v.s.Validate(gojsonschema.NewGoLoader(doc))
+	if err != nil {
+		return err
+	}
+	if res.Valid() {
+		return nil
+	}
+	msgs := make([]string, 0, len(res.Errors()))
+	for _, e := range res.Errors() {
+		msgs = append(msgs, e.String())
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+`,
+	},
+	RuntimeSanthoshTekuri: {
+		std: []string{`bytes`, `encoding/json`, `fmt`},
+		lib: `github.com/santhosh-tekuri/jsonschema/v5`,
+		doc: `*jsonschema.Schema`,
+		adapter: `
+// schemagenCompile compiles schema named name, which is unmarshaled from raw.
+// Schemas which do not declare their draft are draft-04 ones.
+func schemagenCompile(name string, raw []byte, schema interface{}) (*jsonschema.Schema, error) {
+	c := jsonschema.NewCompiler()
+	c.Draft = jsonschema.Draft4
+	if err := c.AddResource(name, bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+	return c.Compile(name)
+}
+
+// schemagenValidator adapts compiled schema to Validator interface.
+type schemagenValidator struct{ s *jsonschema.Schema }
+
+// Validate implements Validator interface.
+func (v schemagenValidator) Validate(doc interface{}) error {
+	return v.s.Validate(doc)
+}
+`,
+	},
+}
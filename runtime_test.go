@@ -0,0 +1,69 @@
+package schemagen
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestRuntimeSet(t *testing.T) {
+	for rt, name := range runtimeNames {
+		var r Runtime
+		if err := r.Set(name); err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+		if r != rt {
+			t.Errorf("want runtime=%d; got %d", rt, r)
+		}
+		if r.String() != name {
+			t.Errorf("want name=%s; got %s", name, r.String())
+		}
+	}
+	var r Runtime
+	if err := r.Set("unknown"); err == nil {
+		t.Errorf("want err!=nil")
+	}
+}
+
+func TestBindSourceRuntimes(t *testing.T) {
+	cases := map[Runtime][]string{
+		RuntimeSigu399: {
+			"\"github.com/sigu-399/gojsonschema\"",
+			"var Schemas = make(map[string]*gojsonschema.JsonSchemaDocument)",
+		},
+		RuntimeXeipuuv: {
+			"\"github.com/xeipuuv/gojsonschema\"",
+			"var Schemas = make(map[string]*gojsonschema.Schema)",
+		},
+		RuntimeSanthoshTekuri: {
+			"\"github.com/santhosh-tekuri/jsonschema/v5\"",
+			"var Schemas = make(map[string]*jsonschema.Schema)",
+		},
+	}
+	for rt, wants := range cases {
+		for _, native := range []bool{false, true} {
+			src, err := bindSource("users", map[string][]byte{"get": []byte("{}")},
+				BindOptions{Native: native, Runtime: rt})
+			if err != nil {
+				t.Fatalf("want err=nil; got %v", err)
+			}
+			if _, err := parser.ParseFile(token.NewFileSet(), "bind.go", src, 0); err != nil {
+				t.Fatalf("want err=nil; got %v", err)
+			}
+			wants := append(wants,
+				"type Validator interface {",
+				"Validators[service] = schemagenValidator{s}",
+				"func (v schemagenValidator) Validate(doc interface{}) error {",
+			)
+			for _, want := range wants {
+				if !strings.Contains(string(src), want) {
+					t.Errorf("want content (%s) to contain %q", src, want)
+				}
+			}
+			if got := strings.Contains(string(src), "nativeValidators[name]"); got != native {
+				t.Errorf("want native=%v; got %v", native, got)
+			}
+		}
+	}
+}
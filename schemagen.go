@@ -1,17 +1,16 @@
 package schemagen
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
-	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 
-	"github.com/rjeczalik/bindata"
 	"github.com/rjeczalik/tools/fs/fsutil"
 )
 
@@ -20,10 +19,6 @@ type schg struct {
 	// grouped by their names.
 	definitions map[string]interface{}
 
-	// services is a helper map that contains service name as key and
-	// path to temporarily created folder for marshaled methods.
-	services map[string]string
-
 	// merge if enabled schemgen creates one schema.go file which
 	// contain schemas from all subdirectories.
 	merge bool
@@ -31,16 +26,114 @@ type schg struct {
 	// pkg is name of package where merged schema.go would be stored.
 	pkg string
 
-	// tmp stores created temporary files/dirs to be removed at the end.
-	tmp []string
-
 	// defFile stores path to definitions file.
 	defFile string
+
+	// defFailed reports whether definitions file exists, but failed to load.
+	defFailed bool
+
+	// inBase is an input directory, references to files outside of it
+	// are not resolved.
+	inBase string
+
+	// files caches JSON files read while resolving references.
+	files map[string]interface{}
+
+	// layers maps directories which have their own definitions file to
+	// the set of definitions visible in them. It is used in Inherit mode.
+	layers map[string]*layer
+
+	// used maps definitions files to names of their definitions which were
+	// injected into at least one schema.
+	used map[string]map[string]bool
+
+	// unused maps definitions files to sorted names of their definitions,
+	// which were not injected into any schema.
+	unused map[string][]string
+
+	// mu guards unused, which is written concurrently in Glob.
+	mu sync.Mutex
+
+	// merged maps paths of schema files, without extension, to the schemas
+	// merged with definitions.
+	merged map[string]map[string]interface{}
+
+	// sources maps services to names of their schemas and the latter to
+	// paths of files the schemas are read from. Services are generated
+	// into output directory.
+	sources map[string]map[string]string
+
+	// fixtures stores paths of directories with sample documents, which are
+	// validated by Test.
+	fixtures []string
+
+	// Conflict is a policy of resolving conflicts between names of schema's
+	// own definitions and shared ones.
+	Conflict Conflict
+
+	// Mirrors maps URL prefixes to local directories, which mirror remote
+	// documents. Remote references are resolved against the mirrored files
+	// and bundled into schemas, so no document is fetched at runtime.
+	Mirrors map[string]string
+
+	// Comments if enabled allows comments and trailing commas in .json
+	// files, they are always allowed in .jsonc and .json5 files. Other
+	// JSON5 extensions are not supported.
+	Comments bool
+
+	// Inherit if enabled makes definitions file found in a subdirectory
+	// extend and override definitions of its parent directory, instead
+	// of excluding the subdirectory from processing.
+	Inherit bool
+
+	// Types if enabled generates Go types of schemas and of definitions
+	// they use. They are stored in `typesFile` file of each service.
+	Types bool
+
+	// Native if enabled translates schemas into Go validation code, which
+	// is stored in `nativeFile` file of each service and used instead of
+	// the interpreter. Schemas which use keywords not supported by
+	// the translation are validated by the interpreter.
+	Native bool
+
+	// Embed if enabled copies schemas as JSON files into output directory
+	// of each service and embeds them with go:embed directive, instead of
+	// storing them as compressed data generated by bindata. It selects
+	// the default Backend.
+	Embed bool
+
+	// Backend if set emits files of each service instead of the default
+	// one, which is BindataBackend or EmbedBackend.
+	Backend Backend
+
+	// Runtime is a library, which validates documents in bind.go files
+	// emitted by the default Backend.
+	Runtime Runtime
+
+	// Lazy if enabled makes bind.go files emitted by the default Backend
+	// compile schemas on first use, instead of panicking at init if any
+	// of them fails.
+	Lazy bool
+
+	// Template if not empty is a path of text/template file, which is used
+	// by the default Backend instead of the default template of bind.go
+	// files. The template is executed with *BindData.
+	Template string
 }
 
 // New creates pointer to new instance of schg struct.
 func New(merge bool) *schg {
-	return &schg{services: make(map[string]string), merge: merge}
+	return &schg{merge: merge}
+}
+
+// clone creates new instance of schg struct with the same settings as s.
+func (s *schg) clone() *schg {
+	c := New(s.merge)
+	c.Inherit, c.Conflict, c.Mirrors = s.Inherit, s.Conflict, s.Mirrors
+	c.Comments, c.Types, c.Native = s.Comments, s.Types, s.Native
+	c.Embed, c.Backend, c.Runtime = s.Embed, s.Backend, s.Runtime
+	c.Lazy, c.Template = s.Lazy, s.Template
+	return c
 }
 
 const (
@@ -51,113 +144,221 @@ const (
 	outputFile = `bind.go`
 )
 
+// errDefinitionsFailed is returned for schemas which need definitions, when
+// definitions file failed to load.
+var errDefinitionsFailed = errors.New(failedDefinitionsErr)
+
+// definitionsKeys are keywords of sections which hold definitions,
+// `$defs` replaced `definitions` since draft 2019-09.
+var definitionsKeys = []string{`definitions`, `$defs`}
+
 const (
-	noDefinitionsErr        = `schemagen: invalid %s file format(missing definitions)`
-	missingDefinitionsErr   = `schemagen: missing definitions`
-	missingOneDefinitionErr = `schemagen: missing definition %s`
-	schemaHasDefinitionsErr = `schemagen: %s file must not have "definitions" filed %#v`
-	cannotOpenFileErr       = `schemagen: cannot open file: %v`
-	cannotWriteToFileErr    = `schemagen: cannot write binding template to file %s: %v`
-	cannotReadFileErr       = `schemagen: cannot read %s, file: %v`
-	cannotRemoveTempDirsErr = `schemagen: cannot remove tmp dir: %v`
+	noDefinitionsErr        = `invalid file format(missing definitions)`
+	missingDefinitionsErr   = `missing definitions`
+	missingOneDefinitionErr = `missing definition %s`
+	localDefinitionsErr     = `invalid %q section`
+	failedDefinitionsErr    = `definitions file failed to load`
+	localConflictErr        = `local definitions conflict with shared ones: %s`
+	localShadowsErr         = `schemagen: %s: local definition %q shadows shared one`
+	sharedShadowsErr        = `schemagen: %s: shared definition %q replaces local one`
+	unknownConflictErr      = `schemagen: unknown conflict policy %q`
+	unknownRuntimeErr       = `schemagen: unknown runtime %q`
+	requiredCycleErr        = `required references form a cycle: %s`
+	notObjectErr            = `document is not an object`
+	invalidSchemaErr        = `schema does not match %s meta-schema at %s: %v`
+	invalidValueErr         = `value at %s does not match its schema: %v`
+	noFixtureSchemaErr      = `no %s schema for sample documents`
+	invalidFixtureSchemaErr = `cannot validate sample documents against %s schema: %v`
+	fixtureMismatchErr      = `sample document does not match %s schema: %v`
+	fixtureMatchErr         = `sample document matches %s schema, but it is expected not to`
+	yamlKeyErr              = `YAML mapping key is not a scalar`
+	yamlAliasErr            = `YAML alias %s refers to itself`
+	yamlMergeErr            = `YAML merge value is not a mapping`
+	noMirrorErr             = `cannot resolve remote reference %q: no mirror directory for it`
+	duplicatedDefinitionErr = `definition %q is defined more than once`
+	duplicatedSchemaErr     = `schema %s of %s service is already defined by %s`
+	duplicatedDefsFileErr   = `definitions are already read from %s`
+	cannotWriteToFileErr    = `cannot write binding template: %v`
+	cannotWriteTypesErr     = `cannot write Go types: %v`
+	cannotWriteNativeErr    = `cannot write validation code: %v`
+	cannotWriteEmbedErr     = `cannot write embedding code: %v`
+	invalidTemplateErr      = `invalid template: %v`
+	nativeFallbackErr       = `schemagen: %s: validated by interpreter, unsupported keywords: %s`
+	unresolvedRefErr        = `cannot resolve reference %q: %v`
+	refOutsideBaseErr       = `reference %q points outside of %s`
+	bundleConflictErr       = `bundled reference %q conflicts with definition`
+	missingPointerErr       = `missing %q`
+	invalidPointerErr       = `invalid JSON pointer %q`
+	overrideDefinitionErr   = `schemagen: %s overrides definition %q of %s`
+	overrideConflictErr     = `schemagen: %s changes meaning of definition %q of %s, which refers to overridden %s`
 )
 
-// loadDefinitions reads all definitions from `definitionsFile` file which needs
-// to be located in 'schemaInBase' directory. If this function fails the program
-// will not parse schema files which contain '$ref' field.
+// loadDefinitions reads all definitions from `definitionsFile` file, or one of
+// its counterparts, which needs to be located in 'schemaInBase' directory.
+// Both `definitions` and `$defs` sections are read. If this function fails
+// the program will not parse schema files which contain '$ref' field.
 func (s *schg) loadDefinitions(schemaInBase string) (err error) {
-	data, err := ioutil.ReadFile(filepath.Join(schemaInBase, definitionsFile))
-	if err != nil {
-		return
-	}
-	if err = json.Unmarshal(data, &s.definitions); err != nil {
+	path := definitionsPath(schemaInBase)
+	if s.definitions, err = s.readDefinitions(path); err != nil {
 		return
 	}
-	var ok bool
-	if s.definitions, ok = s.definitions[`definitions`].(map[string]interface{}); !ok {
-		return fmt.Errorf(noDefinitionsErr, definitionsFile)
+	if err = checkReferences(path, s.definitions, s.definitions); err != nil {
+		s.definitions = nil
 	}
 	return
 }
 
+// readDefinitions reads definitions file located at path and merges its
+// `definitions` and `$defs` sections into one set. Fragments of other files
+// referred by definitions are bundled into the set as well.
+func (s *schg) readDefinitions(path string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fileError(path, nil, err)
+	}
+	doc, err := s.decodeObject(path, data)
+	if err != nil {
+		return nil, err
+	}
+	var found bool
+	defs := make(map[string]interface{})
+	for _, key := range definitionsKeys {
+		cont, ok := doc[key]
+		if !ok {
+			continue
+		}
+		found = true
+		m, ok := cont.(map[string]interface{})
+		if !ok {
+			return nil, newError(path, noDefinitionsErr)
+		}
+		for name, def := range m {
+			if _, ok := defs[name]; ok {
+				return nil, newError(path, duplicatedDefinitionErr, name)
+			}
+			defs[name] = def
+		}
+	}
+	if !found {
+		return nil, newError(path, noDefinitionsErr)
+	}
+	bundled, err := s.bundle(path, defs)
+	if err != nil {
+		return nil, err
+	}
+	if err = injectBundled(path, defs, bundled); err != nil {
+		return nil, err
+	}
+	return defs, nil
+}
+
+// injectBundled adds bundled fragments into def map, it fails if
+// a fragment's name is already taken by another definition.
+func injectBundled(path string, def, bundled map[string]interface{}) error {
+	for name, cont := range bundled {
+		if _, ok := def[name]; ok {
+			return newError(path, bundleConflictErr, name)
+		}
+		def[name] = cont
+	}
+	return nil
+}
+
 // findReferences recursively searches schema for `$ref` token and,
-// if found token has #/definitions/* structure, adds definition name
-// into a return slice.
+// if found token is a JSON pointer with #/definitions/* structure, adds
+// the name of definition it points into to a return slice.
 func (s *schg) findReferences(schema map[string]interface{}) []string {
 	var refs []string
-	for name, cont := range schema {
-		switch reflect.ValueOf(cont).Kind() {
-		case reflect.Map:
-			refs = append(refs, s.findReferences(cont.(map[string]interface{}))...)
-		case reflect.String:
-			if name == `$ref` {
-				toks := strings.Split(cont.(string), `/`)
-				if len(toks) == 3 && toks[0] == `#` && toks[1] == `definitions` {
-					refs = append(refs, toks[2])
-				}
+	walkRefs(schema, func(ref string) {
+		if name, ok := definitionName(ref); ok {
+			refs = append(refs, name)
+		}
+	})
+	return refs
+}
+
+// walkRefs visits every JSON value nested in v, objects and arrays at any
+// depth, and calls fn for each `$ref` token which holds a string.
+func walkRefs(v interface{}, fn func(ref string)) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for name, cont := range v {
+			if ref, ok := cont.(string); ok && name == `$ref` {
+				fn(ref)
+				continue
 			}
+			walkRefs(cont, fn)
+		}
+	case []interface{}:
+		for _, cont := range v {
+			walkRefs(cont, fn)
 		}
 	}
-	return refs
 }
 
 // makeDefinitions extracts required definitions form main `definitionsFile`
 // schema. This guarantees that only references truely used in processing
-// schema will be injected into it.
+// schema will be injected into it. Definitions referred by other definitions
+// are extracted as well, so the result is closed under `$ref`.
 func (s *schg) makeDefinitions(req []string) (map[string]interface{}, error) {
-	if s.definitions == nil || (len(s.definitions) == 0 && len(req) != 0) {
-		return nil, fmt.Errorf(missingDefinitionsErr)
-	}
+	return s.makeLocalDefinitions(req, nil)
+}
+
+// makeLocalDefinitions works like makeDefinitions, but definitions found in
+// local map are taken from it instead of main `definitionsFile` schema.
+// Definitions file is needed only if req refers to a definition which is not
+// a local one.
+func (s *schg) makeLocalDefinitions(req []string,
+	local map[string]interface{}) (map[string]interface{}, error) {
 	def := make(map[string]interface{})
-	for _, tok := range req {
+	for tok, content := range local {
+		def[tok] = content
+	}
+	// queue is a copy of req, so appending to it does not touch caller's data.
+	queue := append([]string(nil), req...)
+	for len(queue) != 0 {
+		tok := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		// already extracted definitions are skipped, this also breaks cycles.
+		if _, ok := def[tok]; ok {
+			continue
+		}
 		content, ok := s.definitions[tok]
+		if !ok && len(s.definitions) == 0 {
+			return nil, fmt.Errorf(missingDefinitionsErr)
+		}
 		if !ok {
 			return nil, fmt.Errorf(missingOneDefinitionErr, tok)
 		}
 		def[tok] = content
+		if m, ok := content.(map[string]interface{}); ok {
+			queue = append(queue, s.findReferences(m)...)
+		}
 	}
 	return def, nil
 }
 
-// dumpToTmpDirs saves unmarshaled binary data into temporary directory.
-// Each service has a separate folder required by gobindata package.
-// Service name and its temporary folder are stored in `services` map.
-func (s *schg) dumpToTmpDirs(path string, data []byte) (err error) {
-	service := filepath.Base(filepath.Dir(path))
+// service returns name of the service which schema file located at path
+// belongs to.
+func (s *schg) service(path string) string {
 	if s.merge {
-		service = s.pkg
+		return s.pkg
 	}
-	fName := strings.TrimSuffix(filepath.Base(path), ".json")
-	if _, ok := s.services[service]; !ok {
-		dir, err := ioutil.TempDir("", "schema_bin")
-		if err != nil {
-			return err
-		}
-		s.tmp = append(s.tmp, dir)
-		s.services[service] = dir
-	}
-	fpath := filepath.Join(s.services[service], fName)
-	file, err := os.OpenFile(fpath, os.O_RDWR|os.O_CREATE, 0755)
-	if err != nil {
-		return
-	}
-	s.tmp = append(s.tmp, fpath)
-	defer file.Close()
-	if _, err = file.Write(data); err != nil {
-		return
-	}
-	return nil
+	return filepath.Base(filepath.Dir(path))
 }
 
 // walkFunc returns function, which is executed for each
 // nondefinition JSON schema file. It creates unmarshaled interface map,
-// injects referenced definitions into it and dumps to temporary directory
-// in order to further processing.
-func (s *schg) walkFunc() filepath.WalkFunc {
+// injects referenced definitions into it and stores it in merged map
+// for further processing. Failures are collected in errs, so
+// the walk goes on. Subtree of directory which definitions cannot be
+// inherited is skipped.
+func (s *schg) walkFunc(errs *Errors) filepath.WalkFunc {
 	var ignDir string
 	return func(path string, info os.FileInfo, extErr error) error {
 		if extErr != nil {
-			return extErr
+			errs.add(fileError(path, nil, extErr))
+			return nil
 		}
 		// if currently in directory with own definitions.json file,
 		// we are ignoring it's content
@@ -169,126 +370,189 @@ func (s *schg) walkFunc() filepath.WalkFunc {
 		// if that's true, we are storing info about this path in ignDir
 		// and continuing ignoring this directory.
 		if info.IsDir() {
-			f := filepath.Join(path, definitionsFile)
+			f := definitionsPath(path)
 			_, err := os.Stat(f)
 			if (err == nil || !os.IsNotExist(err)) && f != s.defFile {
+				if s.Inherit {
+					errs.add(checkDefinitionsFiles(path))
+					if err := s.inheritDefinitions(path); err != nil {
+						errs.add(err)
+						return filepath.SkipDir
+					}
+					return nil
+				}
 				ignDir = path
 				return nil
 			}
 		}
 		// current directory is not ignored and ignored one is left
 		ignDir = ""
-		if info.Name() != definitionsFile && filepath.Ext(info.Name()) == `.json` {
-			data, err := ioutil.ReadFile(path)
-			if err != nil {
-				return err
-			}
-			var mapSchema map[string]interface{}
-			if err := json.Unmarshal(data, &mapSchema); err != nil {
-				return err
-			}
-			def, err := s.makeDefinitions(s.findReferences(mapSchema))
-			if err != nil {
-				return err
-			}
-			if _, ok := mapSchema[`definitions`]; ok {
-				return fmt.Errorf(schemaHasDefinitionsErr, info.Name(), mapSchema)
-			}
-			// inject required definitions into processing schema.
-			mapSchema[`definitions`] = def
-			marshaled, err := json.Marshal(mapSchema)
-			if err != nil {
-				return err
+		if info.IsDir() && isFixturesDir(path) {
+			s.fixtures = append(s.fixtures, path)
+			return filepath.SkipDir
+		}
+		if !isDefinitionsFile(info.Name()) && schemaExts[filepath.Ext(info.Name())] {
+			if s.Inherit {
+				s.definitions = s.layer(filepath.Dir(path)).defs
 			}
-			if err := s.dumpToTmpDirs(path, marshaled); err != nil {
-				return err
+			if err := s.generateSchema(path); err != errDefinitionsFailed {
+				errs.add(fileError(path, nil, err))
 			}
 		}
 		return nil
 	}
 }
 
-// createPaths if necessary, creates service named folders in output path.
-func (s *schg) createPaths(schemaOutBase string) (err error) {
-	for serv := range s.services {
-		path := schemaOutBase
-		if !s.merge && serv != filepath.Base(path) {
-			path = filepath.Join(path, serv)
-		}
-		if err = os.MkdirAll(path, 0755); err != nil {
-			return
-		}
+// addSource records that schema read from path belongs to its service. It
+// fails if the service has already a schema of the same name, e.g. read from
+// file of another extension.
+func (s *schg) addSource(path string) error {
+	serv := s.service(path)
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if s.sources[serv] == nil {
+		s.sources[serv] = make(map[string]string)
 	}
-	return
+	if src, ok := s.sources[serv][name]; ok {
+		return newError(path, duplicatedSchemaErr, name, serv, src)
+	}
+	s.sources[serv][name] = path
+	return nil
+}
+
+// generateSchema reads schema file located at path, injects referenced
+// definitions into it, validates it against its meta-schema, validates its
+// default and example values and stores it in merged map.
+func (s *schg) generateSchema(path string) error {
+	if err := s.addSource(path); err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	mapSchema, err := s.decodeObject(path, data)
+	if err != nil {
+		return err
+	}
+	if err = s.injectDefinitions(path, mapSchema); err != nil {
+		return err
+	}
+	if err = validateSchema(path, mapSchema); err != nil {
+		return err
+	}
+	if err = validateValues(path, mapSchema); err != nil {
+		return err
+	}
+	s.merged[strings.TrimSuffix(path, filepath.Ext(path))] = mapSchema
+	return nil
 }
 
-// saveAsGoBinData creates a `schema.go` source file for each parsed service.
-// Output file contains a compressed data representation of parsed schemas
-// and `_bindata` map which keys represent json methods' name.
-func (s *schg) saveAsGoBinData(schemaOutBase string) (err error) {
-	ch, ret := make(chan *bindata.Config, len(s.services)), make(chan error)
-	for serv, path := range s.services {
-		subdir := serv
-		if s.merge || serv == filepath.Base(schemaOutBase) {
-			subdir = ""
+// injectDefinitions injects into schema read from path all the definitions
+// it needs. These are schema's own definitions, shared definitions referred
+// by schema and fragments of other files it refers to.
+func (s *schg) injectDefinitions(path string, mapSchema map[string]interface{}) error {
+	local, err := s.localDefinitions(path, mapSchema)
+	if err != nil {
+		return err
+	}
+	bundled, err := s.bundle(path, mapSchema)
+	if err != nil {
+		return err
+	}
+	if err = s.bundleRefs(path, local, bundled); err != nil {
+		return err
+	}
+	// bundled fragments and local definitions may refer to shared
+	// definitions.
+	var refs []string
+	for _, ref := range append(append(s.findReferences(mapSchema),
+		s.findReferences(bundled)...), s.findReferences(local)...) {
+		if _, ok := bundled[ref]; !ok {
+			refs = append(refs, ref)
 		}
-		ch <- &bindata.Config{
-			Package:   serv,
-			Input:     []bindata.InputConfig{bindata.InputConfig{Path: path}},
-			Output:    filepath.Join(schemaOutBase, subdir, "schema.go"),
-			Prefix:    path,
-			Recursive: true,
-			Fmt:       true,
+	}
+	def, err := s.makeLocalDefinitions(refs, local)
+	if err != nil {
+		if s.defFailed {
+			// the failure is already reported for definitions file.
+			return errDefinitionsFailed
 		}
+		return err
 	}
-	defer close(ch)
-	for n := min(runtime.GOMAXPROCS(-1), len(s.services)); n > 0; n-- {
-		go func() {
-			for c := range ch {
-				ret <- bindata.Generate(c)
-			}
-		}()
+	s.markUsed(filepath.Dir(path), def, local)
+	if err = injectBundled(path, def, bundled); err != nil {
+		return err
 	}
-	var e error
-	for _ = range s.services {
-		if err := <-ret; err != nil {
-			e = err
+	for _, v := range []interface{}{mapSchema, def} {
+		if err = checkReferences(path, v, def); err != nil {
+			return err
 		}
 	}
-	return e
+	// inject required definitions into processing schema, under the keyword
+	// of draft the schema declares. Definitions are copied, since references
+	// are rewritten to use that keyword.
+	key := definitionsKeyword(mapSchema)
+	def = copyJSON(def).(map[string]interface{})
+	for _, v := range []interface{}{mapSchema, def} {
+		rewriteRefs(v, func(ref string) (string, error) {
+			return definitionsRef(ref, key), nil
+		})
+	}
+	mapSchema[key] = def
+	return nil
 }
 
-// createBindSchemaFiles makes additional bind.go file. The file contains
-// Schemas map which has ready to use JSON schema documents.
-func (s *schg) createBindSchemaFiles(schemaOutBase string) (err error) {
-	for serv := range s.services {
-		subdir := serv
-		if s.merge || serv == filepath.Base(schemaOutBase) {
-			subdir = ""
-		}
-
-		file, err := os.OpenFile(filepath.Join(
-			schemaOutBase, subdir, outputFile), os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0755)
-		if err != nil {
-			return fmt.Errorf(cannotOpenFileErr, err)
+// createPaths if necessary, creates service named folders in output path.
+func (s *schg) createPaths(schemaOutBase string) (err error) {
+	for serv := range s.sources {
+		path := schemaOutBase
+		if !s.merge && serv != filepath.Base(path) {
+			path = filepath.Join(path, serv)
 		}
-		defer file.Close()
-		_, err = file.WriteString(fmt.Sprintf(bindTemplate, serv))
-		if err != nil {
-			return fmt.Errorf(cannotWriteToFileErr, file.Name(), err)
+		if err = os.MkdirAll(path, 0755); err != nil {
+			return fileError(path, nil, err)
 		}
 	}
 	return
 }
 
+// walk loads definitions from schemaInBase, which is an absolute path, and
+// merges them with all the schemas found in the directory tree. All the
+// schemas are walked, so every failure is reported at once.
+func (s *schg) walk(schemaInBase string) error {
+	s.defFile = definitionsPath(schemaInBase)
+	s.inBase, s.files = schemaInBase, nil
+	s.used, s.unused = nil, nil
+	s.merged, s.fixtures = make(map[string]map[string]interface{}), nil
+	s.sources = make(map[string]map[string]string)
+
+	var errs Errors
+	// missing definitions file is not a failure, as long as schemas do not
+	// refer to definitions.
+	err := s.loadDefinitions(schemaInBase)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Println(err)
+	} else {
+		errs.add(err)
+	}
+	s.defFailed = err != nil && !errors.Is(err, os.ErrNotExist)
+	s.layers = map[string]*layer{schemaInBase: newLayer(s.definitions, s.defFile)}
+	errs.add(checkDefinitionsFiles(schemaInBase))
+	errs.add(s.checkCycles(s.layers[schemaInBase], s.defFile))
+	errs.add(filepath.Walk(schemaInBase, s.walkFunc(&errs)))
+	return errs.err()
+}
+
 // Generate loads definitions from schemaInBase/definitions.json file and
 // uses them with other JSON schemas got from folders representing service
 // name. If function successed schemaOutBase directory will contain exacly
 // the same folder structure as in schemaInBase. Each folder will have
-// a schema.go file with binarized schemas collected in '_bindata' map.
+// files emitted by Backend, by default a schema.go file with binarized
+// schemas collected in '_bindata' map and a bind.go file which binds them.
+// Generate does not stop at the first invalid schema, failures of all
+// of them are returned as Errors.
 func (s *schg) Generate(schemaInBase, schemaOutBase string) (err error) {
 	s.definitions = nil
-	s.services = make(map[string]string, 0)
 	s.pkg = filepath.Base(schemaOutBase)
 	if schemaInBase, err = filepath.Abs(filepath.Clean(schemaInBase)); err != nil {
 		return
@@ -296,41 +560,26 @@ func (s *schg) Generate(schemaInBase, schemaOutBase string) (err error) {
 	if schemaOutBase, err = filepath.Abs(filepath.Clean(schemaOutBase)); err != nil {
 		return
 	}
-	s.defFile = filepath.Join(schemaInBase, definitionsFile)
-
-	if err = s.loadDefinitions(schemaInBase); err != nil {
-		log.Println(fmt.Sprintf(cannotReadFileErr, definitionsFile, err))
-	}
-	if err = filepath.Walk(schemaInBase, s.walkFunc()); err != nil {
+	if err = s.walk(schemaInBase); err != nil {
 		return
 	}
-	// remove created temporary files/dirs at the end.
-	defer func() {
-		if e := s.dropTmpDirs(); e != nil {
-			log.Println(fmt.Sprintf(cannotRemoveTempDirsErr, e))
-		}
-	}()
+	s.addUnused(s.findUnused())
 	if err = s.createPaths(schemaOutBase); err != nil {
 		return
 	}
-	if err = s.saveAsGoBinData(schemaOutBase); err != nil {
+	if err = s.saveServices(schemaOutBase); err != nil {
 		return
 	}
-	if err = s.createBindSchemaFiles(schemaOutBase); err != nil {
-		return
+	if s.Types {
+		if err = s.createTypesFiles(schemaOutBase); err != nil {
+			return
+		}
 	}
-	return
-}
-
-// dropTmpDirs removes temporary files/dirs created during Generate's run.
-func (s *schg) dropTmpDirs() (err error) {
-	for _, p := range s.tmp {
-		e := os.RemoveAll(p)
-		if err == nil {
-			err = e
+	if s.Native {
+		if err = s.createNativeFiles(schemaOutBase); err != nil {
+			return
 		}
 	}
-	s.tmp = make([]string, 0)
 	return
 }
 
@@ -359,6 +608,17 @@ func globGopath(gopath string) (paths []path) {
 // Glob generates Go source code for all JSON schemas present in directories
 // specified in GOPATH variable.
 func Glob(merge bool) error {
+	return New(merge).Glob()
+}
+
+// Glob works like package-level Glob function, Go source code for each
+// directory is generated with settings of s. Failures of all the directories
+// are returned as Errors.
+func (s *schg) Glob() error {
+	// unused definitions of the previous call are not reported again.
+	s.mu.Lock()
+	s.unused = nil
+	s.mu.Unlock()
 	var paths []path
 	// get paths for wich Go code for JSON schemas should be generated.
 	for _, p := range strings.Split(os.Getenv("GOPATH"),
@@ -368,6 +628,9 @@ func Glob(merge bool) error {
 		}
 		paths = append(paths, globGopath(p)...)
 	}
+	if s.Inherit {
+		paths = inheritRoots(paths)
+	}
 	ch, ret := make(chan path, len(paths)), make(chan error)
 	for _, r := range paths {
 		ch <- r
@@ -376,47 +639,16 @@ func Glob(merge bool) error {
 	for n := min(runtime.GOMAXPROCS(-1), len(paths)); n > 0; n-- {
 		go func() {
 			for c := range ch {
-				ret <- New(merge).Generate(c.in, c.out)
+				g := s.clone()
+				err := g.Generate(c.in, c.out)
+				s.addUnused(g.unused)
+				ret <- err
 			}
 		}()
 	}
-	var e error
+	var errs Errors
 	for _ = range paths {
-		if err := <-ret; err != nil {
-			e = err
-		}
-	}
-	return e
-}
-
-// bindTemplate is a generic bind.go file template used to bind raw schemas
-// into gojsonschema documents.
-const bindTemplate = `package %[1]s
-
-import (
-	"encoding/json"
-	"fmt"
-
-	"github.com/sigu-399/gojsonschema"
-)
-
-var Schemas = make(map[string]*gojsonschema.JsonSchemaDocument)
-
-func init() {
-	for service, schemaFunc := range _bindata {
-		rawSchema, err := schemaFunc()
-		if err != nil {
-			panic(fmt.Sprintf("%[1]s: %%v", err))
-		}
-		var mapSchema interface{}
-		if err := json.Unmarshal(rawSchema, &mapSchema); err != nil {
-			panic(fmt.Sprintf("%[1]s: %%v", err))
-		}
-		s, err := gojsonschema.NewJsonSchemaDocument(mapSchema)
-		if err != nil {
-			panic(fmt.Sprintf("%[1]s: %%v", err))
-		}
-		Schemas[service] = s
+		errs.add(<-ret)
 	}
+	return errs.err()
 }
-`
@@ -5,6 +5,8 @@ import (
 	"compress/gzip"
 	"encoding/json"
 	"fmt"
+	"go/parser"
+	"go/token"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -77,6 +79,28 @@ func TestFindReferences(t *testing.T) {
 	}
 }
 
+func TestFindReferencesArrays(t *testing.T) {
+	schg := New(false)
+	tests := map[string]string{
+		"allOf": `{"allOf": [{"type": "object"}, {"$ref": "#/definitions/id"}]}`,
+		"anyOf": `{"anyOf": [{"type": "null"}, {"$ref": "#/definitions/id"}]}`,
+		"oneOf": `{"properties": {"x": {"oneOf": [{"$ref": "#/definitions/id"}]}}}`,
+		"items": `{"type": "array", "items": [{"type": "string"}, {"$ref": "#/definitions/id"}]}`,
+		"enum":  `{"enum": [1, "a", {"$ref": "#/definitions/id"}]}`,
+		"depth": `{"allOf": [{"anyOf": [[{"oneOf": [{"$ref": "#/definitions/id"}]}]]}]}`,
+	}
+	for keyword, schema := range tests {
+		refs := findReferencesTest(t, schema, schg)
+		if len(refs) != 1 {
+			t.Errorf("%s: want len(refs)=1; got %d", keyword, len(refs))
+			continue
+		}
+		if refs[0] != "id" {
+			t.Errorf("%s: want refs[0]=\"id\"; got %s", keyword, refs[0])
+		}
+	}
+}
+
 func TestMakeDefinitions(t *testing.T) {
 	schg := New(false)
 	// nil definitions.
@@ -89,6 +113,11 @@ func TestMakeDefinitions(t *testing.T) {
 		t.Fatalf("want err!=nil")
 	}
 
+	// nil definitions empty refs.
+	if defsmap, err = schg.makeDefinitions(nil); err != nil || len(defsmap) != 0 {
+		t.Fatalf("want err=nil and no definitions; got %v, %v", err, defsmap)
+	}
+
 	// empty definitions non empty refs.
 	schg.definitions = make(map[string]interface{})
 	if schg.definitions == nil {
@@ -124,58 +153,82 @@ func TestMakeDefinitions(t *testing.T) {
 	}
 }
 
-func TestDumpToTmpDirs(t *testing.T) {
+func TestMakeDefinitionsTransitive(t *testing.T) {
+	schg := New(false)
+	defs := `{
+		"id": {"type": "integer", "minimum": 1},
+		"user": {"type": "object", "properties": {
+			"id": {"$ref": "#/definitions/id"},
+			"friend": {"$ref": "#/definitions/user"},
+			"address": {"$ref": "#/definitions/address"}}},
+		"address": {"type": "object", "properties": {
+			"owner": {"$ref": "#/definitions/user"},
+			"zip": {"$ref": "#/definitions/zip"}}},
+		"zip": {"type": "string"},
+		"unused": {"type": "null"}
+	}`
+	if err := json.Unmarshal([]byte(defs), &schg.definitions); err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+
+	defsmap, err := schg.makeDefinitions([]string{"user"})
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	for _, name := range []string{"id", "user", "address", "zip"} {
+		if _, ok := defsmap[name]; !ok {
+			t.Errorf("want %q in definitions; got %v", name, defsmap)
+		}
+	}
+	if _, ok := defsmap["unused"]; ok {
+		t.Errorf("want \"unused\" not to be in definitions")
+	}
+
+	// missing transitive definition.
+	delete(schg.definitions, "zip")
+	if _, err = schg.makeDefinitions([]string{"user"}); err == nil {
+		t.Fatalf("want err!=nil")
+	}
+}
+
+func TestServiceSchemas(t *testing.T) {
 	testPaths := map[string]string{
 		filepath.Join("service1", "method1"):   "service1",
-		filepath.Join("service2", "method1"):   "service2",
+		filepath.Join("service2", "method2"):   "service2",
 		filepath.Join("service3", "method100"): "service3",
 		filepath.Join("service4", "method200"): "service4",
 	}
-	schg := New(false)
-	schg.pkg = "serv_dir"
-
-	for path, serv := range testPaths {
-		err := schg.dumpToTmpDirs(path, []byte("sth"))
-		defer schg.dropTmpDirs()
-
-		if err != nil {
-			t.Fatalf("want err=nil; got %v", err)
-		}
-		_, ok := schg.services[serv]
-		if !ok {
-			t.Fatalf("want ok=true")
+	for _, merge := range []bool{false, true} {
+		schg := New(merge)
+		schg.pkg = "serv_dir"
+		schg.merged = make(map[string]map[string]interface{})
+		schg.sources = make(map[string]map[string]string)
+		for path := range testPaths {
+			if err := schg.addSource(path + ".json"); err != nil {
+				t.Fatalf("want err=nil; got %v", err)
+			}
+			schg.merged[path] = map[string]interface{}{"title": filepath.Base(path)}
 		}
-	}
-
-	schg.merge = true
-	schg.services = make(map[string]string)
-	for path, serv := range testPaths {
-		err := schg.dumpToTmpDirs(path, []byte("sth"))
-		if err != nil {
-			t.Fatalf("want err=nil; got %v", err)
+		for path, serv := range testPaths {
+			if merge {
+				serv = "serv_dir"
+			}
+			schemas, err := schg.serviceSchemas(serv)
+			if err != nil {
+				t.Fatalf("want err=nil; got %v", err)
+			}
+			method := filepath.Base(path)
+			if exp := `{"title":"` + method + `"}`; string(schemas[method]) != exp {
+				t.Errorf("want %s schema (merge=%v); got %s", exp, merge, schemas[method])
+			}
 		}
-
-		_, ok := schg.services[serv]
-		if ok {
-			t.Fatalf("want ok=false")
+		if n := len(schg.sources); (merge && n != 1) || (!merge && n != len(testPaths)) {
+			t.Errorf("want services of %v (merge=%v); got %v", testPaths, merge, schg.sources)
 		}
 	}
-	_, ok := schg.services["serv_dir"]
-	if !ok {
-		t.Fatalf("want ok=true")
-	}
-	_, ok = schg.services["schema"]
-	if ok {
-		t.Fatalf("want ok=false")
-	}
 }
 
-func TestSaveAsGoBinData(t *testing.T) {
-	schg := New(false)
-	tmpPath := newSchemaJSONDir(t, "def", "\x44\x55\x50\x41", "")
-
-	schg.services["testservice"] = filepath.Join(tmpPath, "testservice")
-	defer os.RemoveAll(tmpPath)
+func TestBindataBackend(t *testing.T) {
 	out, err := ioutil.TempDir(os.TempDir(), "out")
 	if err != nil {
 		t.Fatalf("want err=nil; got %v", err)
@@ -187,7 +240,9 @@ func TestSaveAsGoBinData(t *testing.T) {
 		t.Fatalf("want err=nil; got %v", err)
 	}
 
-	err = schg.saveAsGoBinData(out)
+	b := &BindataBackend{}
+	err = b.Generate("testservice", filepath.Join(out, "testservice"),
+		map[string][]byte{"testmethod": []byte("\x44\x55\x50\x41")})
 	if err != nil {
 		t.Fatalf("want err=nil; got %v", err)
 	}
@@ -216,11 +271,14 @@ func TestSaveAsGoBinData(t *testing.T) {
 	}
 }
 
-func TestCreateBindSchemaFiles(t *testing.T) {
+func TestSaveServices(t *testing.T) {
 	schg := New(false)
-	tmpPath := newSchemaJSONDir(t, "def", "cont", "")
-	schg.services["testservice"] = filepath.Join(tmpPath, "testservice")
-	defer os.RemoveAll(tmpPath)
+	schg.sources = map[string]map[string]string{
+		"testservice": {"testmethod": filepath.Join("testservice", "testmethod.json")},
+	}
+	schg.merged = map[string]map[string]interface{}{
+		filepath.Join("testservice", "testmethod"): {"type": "object"},
+	}
 	out, err := ioutil.TempDir(os.TempDir(), "out")
 	defer os.RemoveAll(out)
 	if err != nil {
@@ -232,7 +290,7 @@ func TestCreateBindSchemaFiles(t *testing.T) {
 		t.Fatalf("want err=nil; got %v", err)
 	}
 
-	err = schg.createBindSchemaFiles(out)
+	err = schg.saveServices(out)
 	if err != nil {
 		t.Fatalf("want err=nil; got %v", err)
 	}
@@ -530,3 +588,57 @@ func TestGlobNoMerge(t *testing.T) {
 	}
 	testDirs(t, exp, false)
 }
+
+func TestBindSource(t *testing.T) {
+	src, err := bindSource("users", map[string][]byte{
+		"get":         []byte("{}"),
+		"create_user": []byte("{}"),
+		"create-user": []byte("{}"),
+	}, BindOptions{})
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "bind.go", src, 0); err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	for _, want := range []string{
+		"\tSchemaCreateUser  = \"create-user\"\n",
+		"\tSchemaCreateUser2 = \"create_user\"\n",
+		"\tSchemaGet         = \"get\"\n",
+		"func ValidateCreateUser(doc interface{}) error {\n\treturn schemagenValidate(SchemaCreateUser, doc)\n}",
+		"func ValidateCreateUser2(doc interface{}) error {\n\treturn schemagenValidate(SchemaCreateUser2, doc)\n}",
+		"func ValidateGet(doc interface{}) error {\n\treturn schemagenValidate(SchemaGet, doc)\n}",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("want content (%s) to contain %q", src, want)
+		}
+	}
+}
+
+func TestBindSourceLazy(t *testing.T) {
+	for _, native := range []bool{false, true} {
+		src, err := bindSource("users", map[string][]byte{"get": []byte("{}")},
+			BindOptions{Native: native, Lazy: true})
+		if err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+		if _, err := parser.ParseFile(token.NewFileSet(), "bind.go", src, 0); err != nil {
+			t.Fatalf("want err=nil; got %v", err)
+		}
+		for _, want := range []string{
+			"\t\"sort\"\n\t\"strings\"\n\t\"sync\"\n",
+			"func Schema(name string) (*Document, error) {",
+			"func MustLoadAll() {",
+			"\tv, err := Schema(name)\n",
+		} {
+			if !strings.Contains(string(src), want) {
+				t.Errorf("want content (%s) to contain %q", src, want)
+			}
+		}
+		for _, unwanted := range []string{"var Schemas", "panic(fmt.Sprintf("} {
+			if strings.Contains(string(src), unwanted) {
+				t.Errorf("want content (%s) not to contain %q", src, unwanted)
+			}
+		}
+	}
+}
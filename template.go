@@ -0,0 +1,307 @@
+package schemagen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"text/template"
+)
+
+// BindData is the data model of templates of `outputFile` files. Each file
+// binds schemas of one service.
+type BindData struct {
+	// Package is a name of Go package the file belongs to.
+	Package string
+	// Service is a name of the service, which is a name of directory holding
+	// its schemas or, if all of them are merged, a name of output directory.
+	Service string
+	// Schemas are schemas of the service sorted by their names.
+	Schemas []BindSchema
+	// Definitions are sorted names of definitions used by the schemas.
+	Definitions []string
+
+	// Native reports whether validation functions use code generated into
+	// `nativeFile` file.
+	Native bool
+	// Runtime is a name of the runtime, e.g. `xeipuuv`.
+	Runtime string
+	// Lazy reports whether schemas are compiled on first use.
+	Lazy bool
+	// Imports are sorted import paths of standard packages used by the
+	// default template.
+	Imports []string
+	// Library is an import path of the runtime.
+	Library string
+	// Document is a Go type of schemas compiled by the runtime.
+	Document string
+	// Adapter is Go code, which declares `schemagenCompile` function
+	// compiling schemas with the runtime and `schemagenValidator` type
+	// adapting them to Validator interface.
+	Adapter string
+}
+
+// BindSchema describes a schema in BindData.
+type BindSchema struct {
+	// Name is a name of the schema, which is a key of `_bindata` map.
+	Name string
+	// Ident is an exported Go identifier derived from Name, it is unique
+	// within a package.
+	Ident string
+	// ID is a value of `$id` or `id` keyword of the schema, if any.
+	ID string
+	// Title is a value of `title` keyword of the schema, if any.
+	Title string
+	// Definitions are sorted names of definitions used by the schema.
+	Definitions []string
+}
+
+// bindTemplate is the default template of `outputFile` file. Schemas are
+// compiled at init, which panics if any of them fails, or on first use if
+// Lazy is enabled.
+var bindTemplate = template.Must(template.New(outputFile).Parse(`package {{.Package}}
+
+import (
+{{range .Imports}}	{{printf "%q" .}}
+{{end}}
+	{{printf "%q" .Library}}
+)
+
+// Validator validates documents against a schema.
+type Validator interface {
+	// Validate returns nil if doc matches the schema, otherwise the error
+	// describes the violations.
+	Validate(doc interface{}) error
+}
+{{if .Lazy}}
+// Document is a compiled schema.
+type Document struct {
+	// Name is a name of the schema.
+	Name string
+	// Schema is the compiled document.
+	Schema {{.Document}}
+	// Validator validates documents against the schema.
+	Validator
+}
+
+// schemagenDocument holds a schema, which is compiled once.
+type schemagenDocument struct {
+	once sync.Once
+	doc  *Document
+	err  error
+}
+
+// schemagenDocuments maps names of schemas to their documents.
+var schemagenDocuments = make(map[string]*schemagenDocument)
+
+func init() {
+	for name := range _bindata {
+		schemagenDocuments[name] = &schemagenDocument{}
+	}
+}
+
+// Schema returns document of schema named name. The schema is compiled on
+// first call, its failure is returned by every call.
+func Schema(name string) (*Document, error) {
+	d, ok := schemagenDocuments[name]
+	if !ok {
+		return nil, fmt.Errorf("{{.Package}}: unknown schema %q", name)
+	}
+	d.once.Do(func() {
+		d.doc, d.err = schemagenLoad(name)
+	})
+	return d.doc, d.err
+}
+
+// schemagenLoad reads schema named name and compiles it.
+func schemagenLoad(name string) (*Document, error) {
+	rawSchema, err := _bindata[name]()
+	if err != nil {
+		return nil, fmt.Errorf("{{.Package}}: %s: %v", name, err)
+	}
+	var mapSchema interface{}
+	if err := json.Unmarshal(rawSchema, &mapSchema); err != nil {
+		return nil, fmt.Errorf("{{.Package}}: %s: %v", name, err)
+	}
+	s, err := schemagenCompile(name, rawSchema, mapSchema)
+	if err != nil {
+		return nil, fmt.Errorf("{{.Package}}: %s: %v", name, err)
+	}
+	return &Document{Name: name, Schema: s, Validator: schemagenValidator{s}}, nil
+}
+
+// MustLoadAll compiles all the schemas, it panics if any of them fails.
+func MustLoadAll() {
+	names := make([]string, 0, len(schemagenDocuments))
+	for name := range schemagenDocuments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := Schema(name); err != nil {
+			panic(err)
+		}
+	}
+}
+{{else}}
+// Schemas maps names of schemas to their compiled documents.
+var Schemas = make(map[string]{{.Document}})
+
+// Validators maps names of schemas to their validators.
+var Validators = make(map[string]Validator)
+
+func init() {
+	for service, schemaFunc := range _bindata {
+		rawSchema, err := schemaFunc()
+		if err != nil {
+			panic(fmt.Sprintf("{{.Package}}: %v", err))
+		}
+		var mapSchema interface{}
+		if err := json.Unmarshal(rawSchema, &mapSchema); err != nil {
+			panic(fmt.Sprintf("{{.Package}}: %v", err))
+		}
+		s, err := schemagenCompile(service, rawSchema, mapSchema)
+		if err != nil {
+			panic(fmt.Sprintf("{{.Package}}: %v", err))
+		}
+		Schemas[service] = s
+		Validators[service] = schemagenValidator{s}
+	}
+}
+{{end}}{{.Adapter}}
+func schemagenValidate(name string, doc interface{}) error {
+{{- if .Native}}
+	if fn, ok := nativeValidators[name]; ok {
+		if err := fn(doc); err != nil {
+			return fmt.Errorf("{{.Package}}: %s: %v", name, err)
+		}
+		return nil
+	}
+{{- end}}
+{{- if .Lazy}}
+	v, err := Schema(name)
+	if err != nil {
+		return err
+	}
+{{- else}}
+	v := Validators[name]
+{{- end}}
+	if err := v.Validate(doc); err != nil {
+		return fmt.Errorf("{{.Package}}: %s: %v", name, err)
+	}
+	return nil
+}
+{{if .Schemas}}
+// Names of schemas.
+const (
+{{- range .Schemas}}
+	Schema{{.Ident}} = {{printf "%q" .Name}}
+{{- end}}
+)
+{{range .Schemas}}
+// Validate{{.Ident}} validates doc against {{.Name}} schema.
+func Validate{{.Ident}}(doc interface{}) error {
+	return schemagenValidate(Schema{{.Ident}}, doc)
+}
+{{end}}{{end}}`))
+
+// parseTemplate parses template of `outputFile` files read from path.
+func parseTemplate(path string) (*template.Template, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fileError(path, nil, err)
+	}
+	tmpl, err := template.New(filepath.Base(path)).Parse(string(data))
+	if err != nil {
+		return nil, newError(path, invalidTemplateErr, err)
+	}
+	return tmpl, nil
+}
+
+// bindIdents returns Go identifiers of schemas named by sorted names, which
+// are unique within a package. Names of schemas may differ only by characters
+// which are not allowed in identifiers.
+func bindIdents(names []string) []string {
+	idents := make([]string, len(names))
+	used := make(map[string]bool, len(names))
+	for i, name := range names {
+		idents[i] = goName(name)
+		for n := 2; used[idents[i]]; n++ {
+			idents[i] = fmt.Sprintf("%s%d", goName(name), n)
+		}
+		used[idents[i]] = true
+	}
+	return idents
+}
+
+// bindData returns data of `outputFile` file of package pkg, which binds
+// schemas. Schemas maps their names to their JSON documents.
+func bindData(pkg string, schemas map[string][]byte, opts BindOptions) *BindData {
+	ra := runtimeAdapters[opts.Runtime]
+	data := &BindData{
+		Package:  pkg,
+		Service:  pkg,
+		Native:   opts.Native,
+		Runtime:  runtimeNames[opts.Runtime],
+		Lazy:     opts.Lazy,
+		Imports:  ra.std,
+		Library:  ra.lib,
+		Document: ra.doc,
+		Adapter:  ra.adapter,
+	}
+	if opts.Lazy {
+		data.Imports = append([]string{`sort`, `sync`}, ra.std...)
+		sort.Strings(data.Imports)
+	}
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	idents := bindIdents(names)
+	defs := make(map[string]bool)
+	for i, name := range names {
+		bs := BindSchema{Name: name, Ident: idents[i]}
+		var schema map[string]interface{}
+		if err := json.Unmarshal(schemas[name], &schema); err == nil {
+			bs.ID, _ = schema[`$id`].(string)
+			if bs.ID == `` {
+				bs.ID, _ = schema[`id`].(string)
+			}
+			bs.Title, _ = schema[`title`].(string)
+			section, _ := schema[definitionsKeyword(schema)].(map[string]interface{})
+			for def := range section {
+				bs.Definitions = append(bs.Definitions, def)
+				if !defs[def] {
+					defs[def] = true
+					data.Definitions = append(data.Definitions, def)
+				}
+			}
+			sort.Strings(bs.Definitions)
+		}
+		data.Schemas = append(data.Schemas, bs)
+	}
+	sort.Strings(data.Definitions)
+	return data
+}
+
+// bindSource returns `outputFile` file of package pkg, which binds schemas.
+// The file is generated with opts.Template or with the default one if it is
+// nil. Source of Go code is formatted.
+func bindSource(pkg string, schemas map[string][]byte, opts BindOptions) ([]byte, error) {
+	tmpl := opts.Template
+	if tmpl == nil {
+		tmpl = bindTemplate
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, bindData(pkg, schemas, opts)); err != nil {
+		return nil, err
+	}
+	if src, err := format.Source(buf.Bytes()); err == nil {
+		return src, nil
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,98 @@
+package schemagen
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBindData(t *testing.T) {
+	data := bindData("users", map[string][]byte{
+		"get": []byte(`{"$id": "http://example.com/get", "title": "Get user",
+			"definitions": {"id": {}, "name": {}}}`),
+		"create_user": []byte(`{"id": "http://example.com/create", "definitions": {"id": {}}}`),
+		"create-user": []byte(`{"title": "Create user"}`),
+	}, BindOptions{Runtime: RuntimeXeipuuv, Lazy: true})
+	want := &BindData{
+		Package: "users",
+		Service: "users",
+		Schemas: []BindSchema{
+			{Name: "create-user", Ident: "CreateUser", Title: "Create user"},
+			{Name: "create_user", Ident: "CreateUser2", ID: "http://example.com/create",
+				Definitions: []string{"id"}},
+			{Name: "get", Ident: "Get", ID: "http://example.com/get", Title: "Get user",
+				Definitions: []string{"id", "name"}},
+		},
+		Definitions: []string{"id", "name"},
+		Runtime:     "xeipuuv",
+		Lazy:        true,
+		Imports:     []string{"encoding/json", "errors", "fmt", "sort", "strings", "sync"},
+		Library:     "github.com/xeipuuv/gojsonschema",
+		Document:    "*gojsonschema.Schema",
+		Adapter:     runtimeAdapters[RuntimeXeipuuv].adapter,
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("want data=%+v; got %+v", want, data)
+	}
+}
+
+func TestGenerateTemplate(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		"definitions.json": `{"definitions": {"id": {"type": "integer"}}}`,
+		"users/get.json":   `{"title": "Get user", "$ref": "#/definitions/id"}`,
+		"users/put.json":   `{"type": "object"}`,
+		"bind.tmpl": `package {{.Package}}
+
+// Registry of {{.Service}} using {{range .Definitions}}{{.}}{{end}}.
+var Registry = map[string]string{
+{{- range .Schemas}}
+	{{printf "%q" .Name}}: {{printf "%q" .Title}},
+{{- end}}
+}
+`,
+	})
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+	g := New(false)
+	g.Template = filepath.Join(dir, "bind.tmpl")
+	if err := g.Generate(dir, out); err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	src, err := ioutil.ReadFile(filepath.Join(out, "users", outputFile))
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	want := `package users
+
+// Registry of users using id.
+var Registry = map[string]string{
+	"get": "Get user",
+	"put": "",
+}
+`
+	if string(src) != want {
+		t.Errorf("want bind.go=%q; got %q", want, src)
+	}
+}
+
+func TestGenerateTemplateInvalid(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		"definitions.json": `{"definitions": {}}`,
+		"users/get.json":   `{"type": "object"}`,
+		"bind.tmpl":        `package {{.Package`,
+	})
+	defer os.RemoveAll(dir)
+	g := New(false)
+	g.Template = filepath.Join(dir, "bind.tmpl")
+	err := g.Generate(dir, filepath.Join(dir, "out"))
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("want err to be *Error; got %v", err)
+	}
+	if e.Path != g.Template {
+		t.Errorf("want path=%s; got %s", g.Template, e.Path)
+	}
+}
@@ -0,0 +1,66 @@
+package schemagen
+
+import "sort"
+
+// markUsed records shared definitions of def map as used by a schema located
+// in dir. Schema's own definitions, found in local map, are not recorded.
+func (s *schg) markUsed(dir string, def, local map[string]interface{}) {
+	l := s.layer(dir)
+	if s.used == nil {
+		s.used = make(map[string]map[string]bool)
+	}
+	for name := range def {
+		if _, ok := local[name]; ok {
+			continue
+		}
+		file := l.files[name]
+		if s.used[file] == nil {
+			s.used[file] = make(map[string]bool)
+		}
+		s.used[file][name] = true
+	}
+}
+
+// findUnused returns names of definitions which were not used by any schema,
+// grouped by definitions files they come from.
+func (s *schg) findUnused() map[string][]string {
+	unused := make(map[string][]string)
+	seen := make(map[string]map[string]bool)
+	for _, l := range s.layers {
+		for name, file := range l.files {
+			if s.used[file][name] || seen[file][name] {
+				continue
+			}
+			if seen[file] == nil {
+				seen[file] = make(map[string]bool)
+			}
+			seen[file][name] = true
+			unused[file] = append(unused[file], name)
+		}
+	}
+	for _, names := range unused {
+		sort.Strings(names)
+	}
+	return unused
+}
+
+// addUnused adds unused definitions to the ones reported by s.Unused.
+func (s *schg) addUnused(unused map[string][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.unused == nil {
+		s.unused = make(map[string][]string)
+	}
+	for file, names := range unused {
+		s.unused[file] = append(s.unused[file], names...)
+	}
+}
+
+// Unused returns names of definitions which were not referenced, directly or
+// through other definitions, by any schema processed during last Generate or
+// Glob call. Names are grouped by definitions files they come from.
+func (s *schg) Unused() map[string][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.unused
+}
@@ -0,0 +1,77 @@
+package schemagen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUnused(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		definitionsFile: `{"definitions": {"id": {"type": "integer"},
+			"user": {"properties": {"id": {"$ref": "#/definitions/id"}}},
+			"zip": {"type": "string"},
+			"dead": {"$ref": "#/definitions/deader"},
+			"deader": {}}}`,
+		"sub/" + definitionsFile:  `{"definitions": {"local": {}, "zip": {"type": "number"}}}`,
+		"sub/service/method.json": `{"$ref": "#/definitions/zip"}`,
+		"other/method.json": `{"$ref": "#/definitions/user",
+			"definitions": {"private": {}}}`,
+	})
+	defer os.RemoveAll(dir)
+	out, err := ioutil.TempDir(os.TempDir(), "out")
+	if err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	defer os.RemoveAll(out)
+
+	schg := New(false)
+	if err = schg.Generate(dir, out); err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	exp := map[string][]string{
+		filepath.Join(dir, definitionsFile): {"dead", "deader", "zip"},
+	}
+	if got := schg.Unused(); !reflect.DeepEqual(got, exp) {
+		t.Errorf("want unused=%v; got %v", exp, got)
+	}
+
+	schg.Inherit = true
+	if err = schg.Generate(dir, out); err != nil {
+		t.Fatalf("want err=nil; got %v", err)
+	}
+	exp = map[string][]string{
+		filepath.Join(dir, definitionsFile):        {"dead", "deader", "zip"},
+		filepath.Join(dir, "sub", definitionsFile): {"local"},
+	}
+	if got := schg.Unused(); !reflect.DeepEqual(got, exp) {
+		t.Errorf("want unused=%v; got %v", exp, got)
+	}
+}
+
+func TestUnusedGlob(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		"schema/p/" + definitionsFile: `{"definitions": {"id": {"type": "integer"}, "zip": {}}}`,
+		"schema/p/service/m.json":     `{"$ref": "#/definitions/id"}`,
+		"src/p/p.go":                  "package p\n",
+	})
+	defer os.RemoveAll(dir)
+	defer os.Setenv("GOPATH", os.Getenv("GOPATH"))
+	os.Setenv("GOPATH", dir)
+
+	schg := New(true)
+	exp := map[string][]string{
+		filepath.Join(dir, "schema", "p", definitionsFile): {"zip"},
+	}
+	// definitions are reported once, even if Glob is called again.
+	for i := 0; i < 2; i++ {
+		if err := schg.Glob(); err != nil {
+			t.Fatalf("want err=nil (i=%d); got %v", i, err)
+		}
+		if got := schg.Unused(); !reflect.DeepEqual(got, exp) {
+			t.Errorf("want unused=%v (i=%d); got %v", exp, i, got)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package schemagen
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// metaSchemas maps locations of meta-schemas, without scheme and fragment,
+// to their documents.
+var metaSchemas = map[string]string{
+	`json-schema.org/draft-04/schema`: metaSchemaDraft04,
+	`json-schema.org/draft-06/schema`: metaSchemaDraft06,
+	`json-schema.org/draft-07/schema`: metaSchemaDraft07,
+}
+
+// defaultMetaSchema is a location of meta-schema used for schemas which do not
+// declare one with `$schema` keyword.
+const defaultMetaSchema = `json-schema.org/draft-04/schema`
+
+// compiled holds meta-schemas compiled on first use, they are shared by all
+// Generate's runs.
+var compiled struct {
+	once    sync.Once
+	schemas map[string]*gojsonschema.Schema
+	err     error
+}
+
+// metaSchema returns compiled meta-schema of the draft declared by schema.
+// Second return value is false if the draft's meta-schema is not bundled.
+func metaSchema(schema map[string]interface{}) (*gojsonschema.Schema, string, bool, error) {
+	compiled.once.Do(func() {
+		compiled.schemas = make(map[string]*gojsonschema.Schema, len(metaSchemas))
+		for loc, doc := range metaSchemas {
+			s, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(doc))
+			if err != nil {
+				compiled.err = err
+				return
+			}
+			compiled.schemas[loc] = s
+		}
+	})
+	if compiled.err != nil {
+		return nil, "", false, compiled.err
+	}
+	loc := defaultMetaSchema
+	if draft, ok := schema[`$schema`].(string); ok {
+		loc = strings.TrimSuffix(draft, `#`)
+		if i := strings.Index(loc, `://`); i != -1 {
+			loc = loc[i+3:]
+		}
+	}
+	s, ok := compiled.schemas[loc]
+	return s, loc, ok, nil
+}
+
+// validateSchema validates schema, read from path, against meta-schema of
+// the draft it declares with `$schema` keyword, draft-04 is assumed if there
+// is none. Schemas of drafts which meta-schemas are not bundled are not
+// validated. Every violation is reported with separate *Error.
+func validateSchema(path string, schema map[string]interface{}) error {
+	meta, loc, ok, err := metaSchema(schema)
+	if err != nil || !ok {
+		return err
+	}
+	res, err := meta.Validate(gojsonschema.NewGoLoader(schema))
+	if err != nil {
+		return newError(path, invalidSchemaErr, loc, `#`, err)
+	}
+	var errs Errors
+	for _, e := range res.Errors() {
+		ptr := strings.TrimPrefix(e.Context().String(`/`), `(root)`)
+		errs.add(newError(path, invalidSchemaErr, loc, `#`+ptr, e.Description()))
+	}
+	return errs.err()
+}
+
+// walkSchemas calls fn for schema v and every its subschema along with JSON
+// pointers to them. Values of keywords which are not schemas, like `enum`
+// or `default`, are not walked.
+func walkSchemas(v interface{}, ptr string, fn func(string, map[string]interface{})) {
+	schema, ok := v.(map[string]interface{})
+	if !ok {
+		return
+	}
+	fn(ptr, schema)
+	for _, key := range []string{`additionalProperties`, `additionalItems`, `not`,
+		`if`, `then`, `else`, `contains`, `propertyNames`} {
+		walkSchemas(schema[key], ptr+`/`+key, fn)
+	}
+	for _, key := range []string{`properties`, `patternProperties`, `dependencies`,
+		`definitions`, `$defs`} {
+		if m, ok := schema[key].(map[string]interface{}); ok {
+			for name, sub := range m {
+				walkSchemas(sub, ptr+`/`+key+`/`+escapeToken(name), fn)
+			}
+		}
+	}
+	for _, key := range []string{`items`, `allOf`, `anyOf`, `oneOf`} {
+		switch sub := schema[key].(type) {
+		case map[string]interface{}:
+			walkSchemas(sub, ptr+`/`+key, fn)
+		case []interface{}:
+			for i, sub := range sub {
+				walkSchemas(sub, ptr+`/`+key+`/`+strconv.Itoa(i), fn)
+			}
+		}
+	}
+}
+
+// validateValues validates `default` and `examples` values found in schema,
+// read from path, against subschemas which own them. Every mismatch is
+// reported with separate *Error, which points to the offending value.
+func validateValues(path string, schema map[string]interface{}) error {
+	var errs Errors
+	walkSchemas(schema, ``, func(ptr string, sub map[string]interface{}) {
+		// ptrs keep the order of values, which are the default one followed
+		// by examples.
+		var ptrs []string
+		values := make(map[string]interface{})
+		if def, ok := sub[`default`]; ok {
+			ptrs = append(ptrs, ptr+`/default`)
+			values[ptr+`/default`] = def
+		}
+		if examples, ok := sub[`examples`].([]interface{}); ok {
+			for i, example := range examples {
+				ptrs = append(ptrs, ptr+`/examples/`+strconv.Itoa(i))
+				values[ptrs[len(ptrs)-1]] = example
+			}
+		}
+		if len(ptrs) == 0 {
+			return
+		}
+		// the subschema is validated as a part of the whole schema, so its
+		// references to definitions are resolved.
+		doc := schema
+		if ptr != `` {
+			doc = make(map[string]interface{}, len(schema)+1)
+			for key, cont := range schema {
+				doc[key] = cont
+			}
+			doc[`$ref`] = `#` + escapeFragment(ptr)
+		}
+		owner, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(doc))
+		if err != nil {
+			errs.add(newError(path, invalidValueErr, `#`+ptr, err))
+			return
+		}
+		for _, val := range ptrs {
+			res, err := owner.Validate(gojsonschema.NewGoLoader(values[val]))
+			if err != nil {
+				errs.add(newError(path, invalidValueErr, `#`+val, err))
+				continue
+			}
+			for _, e := range res.Errors() {
+				errs.add(newError(path, invalidValueErr, `#`+val, e.Description()))
+			}
+		}
+	})
+	return errs.err()
+}
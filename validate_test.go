@@ -0,0 +1,130 @@
+package schemagen
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateSchema(t *testing.T) {
+	cases := []struct {
+		schema string
+		ptrs   []string
+	}{
+		{`{"type": "object", "properties": {"a": {"type": "string"}}}`, nil},
+		{`{"type": "object", "properties": {"a": {"type": "strin"}}}`,
+			[]string{"#/properties/a/type"}},
+		{`{"required": "a", "minLength": -1}`, []string{"#/required", "#/minLength"}},
+		{`{"$schema": "http://json-schema.org/draft-04/schema#",
+			"minimum": 1, "exclusiveMinimum": 0}`, []string{"#/exclusiveMinimum"}},
+		{`{"$schema": "http://json-schema.org/draft-07/schema#",
+			"minimum": 1, "exclusiveMinimum": 0}`, nil},
+		{`{"$schema": "http://json-schema.org/draft-06/schema#",
+			"properties": {"a": {"const": 1, "items": 1}}}`, []string{"#/properties/a/items"}},
+		{`{"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"type": "strin"}`, nil},
+	}
+	for i, cas := range cases {
+		var schema map[string]interface{}
+		if err := json.Unmarshal([]byte(cas.schema), &schema); err != nil {
+			t.Fatalf("want err=nil (i=%d); got %v", i, err)
+		}
+		err := validateSchema("m.json", schema)
+		if cas.ptrs == nil {
+			if err != nil {
+				t.Errorf("want err=nil (i=%d); got %v", i, err)
+			}
+			continue
+		}
+		errs, ok := err.(Errors)
+		if !ok {
+			t.Errorf("want Errors (i=%d); got %v", i, err)
+			continue
+		}
+		for _, ptr := range cas.ptrs {
+			if !strings.Contains(err.Error(), " at "+ptr+": ") {
+				t.Errorf("want error at %s (i=%d); got %v", ptr, i, err)
+			}
+		}
+		for _, err := range errs {
+			if ferr, ok := err.(*Error); !ok || ferr.Path != "m.json" {
+				t.Errorf("want *Error for m.json (i=%d); got %v", i, err)
+			}
+		}
+	}
+}
+
+func TestWalkValidate(t *testing.T) {
+	dir := newSchemaTree(t, map[string]string{
+		"definitions.json": `{"definitions": {"a": {"type": "intger"}}}`,
+		"s/m.json":         `{"type": "object", "properties": {"a": {"$ref": "#/definitions/a"}}}`,
+		"s/n.json":         `{"type": "object"}`,
+	})
+	defer os.RemoveAll(dir)
+	_, err := walkTest(t, New(false), dir)
+	errs, ok := err.(Errors)
+	if !ok || len(errs) == 0 {
+		t.Fatalf("want Errors; got %v", err)
+	}
+	for _, err := range errs {
+		ferr, ok := err.(*Error)
+		if !ok || ferr.Path != filepath.Join(dir, "s", "m.json") {
+			t.Fatalf("want *Error for s/m.json; got %v", err)
+		}
+		if !strings.Contains(ferr.Error(), "#/definitions/a/type") {
+			t.Errorf("want error at #/definitions/a/type; got %v", ferr)
+		}
+	}
+}
+
+func TestValidateValues(t *testing.T) {
+	cases := []struct {
+		schema string
+		ptrs   []string
+	}{
+		{`{"type": "object", "default": {}, "properties": {"a": {"type": "string",
+			"default": "x", "examples": ["y", "z"]}}}`, nil},
+		{`{"type": "object", "default": 1, "properties": {"a": {"type": "string",
+			"default": 1, "examples": ["y", 2]}}}`,
+			[]string{"#/default", "#/properties/a/default", "#/properties/a/examples/1"}},
+		{`{"definitions": {"n": {"type": "integer", "minimum": 1}}, "properties": {
+			"a": {"$ref": "#/definitions/n", "default": 0},
+			"b": {"items": [{"$ref": "#/definitions/n"}], "default": [1, 0]},
+			"c": {"allOf": [{"$ref": "#/definitions/n", "examples": [2]}]}}}`,
+			[]string{"#/properties/a/default"}},
+		{`{"definitions": {"n": {"type": "integer", "default": "1"}},
+			"properties": {"a/b": {"enum": ["x"], "default": "y"}}}`,
+			[]string{"#/definitions/n/default", "#/properties/a~1b/default"}},
+		{`{"$schema": "http://json-schema.org/draft-07/schema#", "id": "http://x/m.json",
+			"properties": {"a": {"const": "x", "examples": ["x", "y"]}}}`,
+			[]string{"#/properties/a/examples/1"}},
+		{`{"properties": {"100%": {"type": "integer", "default": 1},
+			"5%#": {"type": "integer", "default": "5"}}}`,
+			[]string{"#/properties/5%#/default"}},
+	}
+	for i, cas := range cases {
+		var schema map[string]interface{}
+		if err := json.Unmarshal([]byte(cas.schema), &schema); err != nil {
+			t.Fatalf("want err=nil (i=%d); got %v", i, err)
+		}
+		err := validateValues("m.json", schema)
+		if cas.ptrs == nil {
+			if err != nil {
+				t.Errorf("want err=nil (i=%d); got %v", i, err)
+			}
+			continue
+		}
+		errs, ok := err.(Errors)
+		if !ok || len(errs) != len(cas.ptrs) {
+			t.Errorf("want %d errors (i=%d); got %v", len(cas.ptrs), i, err)
+			continue
+		}
+		for _, ptr := range cas.ptrs {
+			if !strings.Contains(err.Error(), " at "+ptr+" ") {
+				t.Errorf("want error at %s (i=%d); got %v", ptr, i, err)
+			}
+		}
+	}
+}